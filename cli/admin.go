@@ -111,13 +111,13 @@ func adminUserCmd(cmd *cli.Cmd) {
 			if *admin != user.IsAdmin {
 				user.IsAdmin = *admin
 			}
-			conch.UpdateUser(string(user.Email), update, *notify)
+			fatalIf(conch.UpdateUser(string(user.Email), update, *notify))
 		}
 	})
 
 	cmd.Command("delete rm", "remove the specified user", func(cmd *cli.Cmd) {
 		cmd.Action = func() {
-			conch.DeleteUser(string(user.Email))
+			fatalIf(conch.DeleteUser(string(user.Email)))
 			display(conch.GetAllUsers())
 		}
 	})
@@ -149,7 +149,7 @@ func adminUserCmd(cmd *cli.Cmd) {
 
 		cmd.Command("delete rm", "remove a token for the given user", func(cmd *cli.Cmd) {
 			cmd.Action = func() {
-				conch.DeleteUserToken(string(user.Email), token.Name)
+				fatalIf(conch.DeleteUserToken(string(user.Email), token.Name))
 				display(conch.GetUserTokens(string(user.Email)))
 			}
 		})
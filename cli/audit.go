@@ -0,0 +1,403 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	cli "github.com/jawher/mow.cli"
+	"github.com/joyent/kosh/conch"
+	"github.com/joyent/kosh/conch/types"
+)
+
+// The outcomes of auditing a device
+const (
+	auditMatch      = "match"      // on the floor where Conch has it
+	auditMisplaced  = "misplaced"  // on the floor, but Conch has it in another rack or RU
+	auditMissing    = "missing"    // Conch has it in a rack but it wasn't found on the floor
+	auditUnexpected = "unexpected" // on the floor, but Conch doesn't have it in a rack
+)
+
+var auditOrder = map[string]int{
+	auditMatch:      0,
+	auditMisplaced:  1,
+	auditMissing:    2,
+	auditUnexpected: 3,
+}
+
+// auditScan is a device found in a rack during a physical audit
+type auditScan struct {
+	Rack          string
+	RackUnitStart int
+	SerialNumber  string
+}
+
+// readAuditScans reads rows of rack,ru,serial, a header row is skipped
+func readAuditScans(r io.Reader) ([]auditScan, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+
+	var scans []auditScan
+	for n := 1; ; n++ {
+		record, e := reader.Read()
+		if e == io.EOF {
+			break
+		}
+		if e != nil {
+			return nil, e
+		}
+
+		ru, e := strconv.Atoi(strings.TrimSpace(record[1]))
+		if e != nil {
+			if n == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("row %d: %q is not a rack unit", n, record[1])
+		}
+		scan := auditScan{
+			Rack:          strings.TrimSpace(record[0]),
+			RackUnitStart: ru,
+			SerialNumber:  strings.TrimSpace(record[2]),
+		}
+		if scan.Rack == "" || scan.SerialNumber == "" {
+			return nil, fmt.Errorf("row %d: expected rack,ru,serial", n)
+		}
+		scans = append(scans, scan)
+	}
+	return scans, nil
+}
+
+// auditFinding is where a device is on the floor compared to where Conch has
+// it
+type auditFinding struct {
+	Status             string `json:"status"`
+	SerialNumber       string `json:"serial_number"`
+	Rack               string `json:"rack,omitempty"`
+	RackUnitStart      int    `json:"rack_unit_start,omitempty"`
+	ConchRack          string `json:"conch_rack,omitempty"`
+	ConchRackUnitStart int    `json:"conch_rack_unit_start,omitempty"`
+	Note               string `json:"note,omitempty"`
+}
+
+// auditReport is the finding for every device seen on the floor or in Conch
+type auditReport []auditFinding
+
+func (r auditReport) Len() int      { return len(r) }
+func (r auditReport) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+func (r auditReport) Less(i, j int) bool {
+	if r[i].Status != r[j].Status {
+		return auditOrder[r[i].Status] < auditOrder[r[j].Status]
+	}
+	if r[i].Rack != r[j].Rack {
+		return r[i].Rack < r[j].Rack
+	}
+	if r[i].RackUnitStart != r[j].RackUnitStart {
+		return r[i].RackUnitStart > r[j].RackUnitStart
+	}
+	if r[i].ConchRack != r[j].ConchRack {
+		return r[i].ConchRack < r[j].ConchRack
+	}
+	return r[i].ConchRackUnitStart > r[j].ConchRackUnitStart
+}
+
+// Headers returns the list of headers for the table view
+func (r auditReport) Headers() []string {
+	return []string{
+		"Status",
+		"Serial Number",
+		"Floor",
+		"Conch",
+		"Note",
+	}
+}
+
+// ForEach iterates over each item in the list and applies a function to it
+func (r auditReport) ForEach(do func([]string)) {
+	location := func(rack string, ru int) string {
+		if rack == "" {
+			return ""
+		}
+		return fmt.Sprintf("%s RU %d", rack, ru)
+	}
+	for _, f := range r {
+		do([]string{
+			f.Status,
+			f.SerialNumber,
+			location(f.Rack, f.RackUnitStart),
+			location(f.ConchRack, f.ConchRackUnitStart),
+			f.Note,
+		})
+	}
+}
+
+// auditFix is the change to one rack that makes Conch match the floor.
+// Deletes are applied before assignments.
+type auditFix struct {
+	Rack   string                      `json:"rack"`
+	RackID types.UUID                  `json:"rack_id"`
+	Delete types.RackAssignmentDeletes `json:"delete,omitempty"`
+	Assign types.RackAssignmentUpdates `json:"assign,omitempty"`
+}
+
+// auditPlan is the set of changes that makes Conch match the floor
+type auditPlan []auditFix
+
+// auditLocation is a slot in a rack
+type auditLocation struct {
+	rack string
+	ru   int
+}
+
+// auditRack is a rack referenced by the scan data, with what Conch has in it
+type auditRack struct {
+	rack        types.Rack
+	assignments map[int]types.RackAssignment
+	fix         auditFix
+}
+
+// auditRacks compares the scans with the assignments of each rack they
+// reference, returning the report and the plan to fix the differences
+func auditRacks(c *conch.Client, scans []auditScan) (auditReport, auditPlan, error) {
+	var (
+		names   []string
+		racks   = map[string]*auditRack{}
+		placed  = map[string]auditLocation{} // where Conch has each device
+		outside []string                     // racks not in the scan that devices were moved out of
+	)
+	for _, scan := range scans {
+		if _, ok := racks[scan.Rack]; ok {
+			continue
+		}
+		rack, e := c.GetRackByName(scan.Rack)
+		if e == nil && rack.ID == (types.UUID{}) {
+			e = errNotFound("could not find rack %s", scan.Rack)
+		}
+		if e != nil {
+			return nil, nil, e
+		}
+		assignments, e := c.GetRackAssignments(rack.ID)
+		if e != nil {
+			return nil, nil, e
+		}
+
+		r := &auditRack{
+			rack:        rack,
+			assignments: map[int]types.RackAssignment{},
+			fix:         auditFix{Rack: scan.Rack, RackID: rack.ID},
+		}
+		for _, a := range assignments {
+			r.assignments[int(a.RackUnitStart)] = a
+			if a.DeviceSerialNumber != "" {
+				placed[string(a.DeviceSerialNumber)] = auditLocation{scan.Rack, int(a.RackUnitStart)}
+			}
+		}
+		names = append(names, scan.Rack)
+		racks[scan.Rack] = r
+	}
+
+	var report auditReport
+	seen := map[string]bool{}
+	for _, scan := range scans {
+		r := racks[scan.Rack]
+		finding := auditFinding{
+			SerialNumber:  scan.SerialNumber,
+			Rack:          scan.Rack,
+			RackUnitStart: scan.RackUnitStart,
+		}
+		if seen[scan.SerialNumber] {
+			finding.Status = auditUnexpected
+			finding.Note = "scanned more than once"
+			report = append(report, finding)
+			continue
+		}
+		seen[scan.SerialNumber] = true
+
+		if where, ok := placed[scan.SerialNumber]; ok {
+			finding.ConchRack = where.rack
+			finding.ConchRackUnitStart = where.ru
+		} else {
+			device, e := c.GetDeviceBySerial(scan.SerialNumber)
+			switch {
+			case conch.IsNotFound(e):
+				finding.Note = "unknown to Conch"
+			case e != nil:
+				return nil, nil, e
+			case device.Location.Rack != "":
+				// the device is in a rack that wasn't scanned, it has to be
+				// removed from there before it can be assigned here
+				finding.ConchRack = string(device.Location.Rack)
+				finding.ConchRackUnitStart = int(device.Location.RackUnitStart)
+				other, ok := racks[finding.ConchRack]
+				if !ok {
+					rack, e := c.GetRackByName(finding.ConchRack)
+					if e == nil && rack.ID == (types.UUID{}) {
+						e = errNotFound("could not find rack %s", finding.ConchRack)
+					}
+					if e != nil {
+						return nil, nil, e
+					}
+					other = &auditRack{fix: auditFix{Rack: finding.ConchRack, RackID: rack.ID}}
+					outside = append(outside, finding.ConchRack)
+					racks[finding.ConchRack] = other
+				}
+				other.fix.Delete = append(other.fix.Delete, types.RackAssignmentDelete{
+					DeviceID:      device.ID,
+					RackUnitStart: device.Location.RackUnitStart,
+				})
+			}
+		}
+
+		switch {
+		case finding.ConchRack == scan.Rack && finding.ConchRackUnitStart == scan.RackUnitStart:
+			finding.Status = auditMatch
+		case finding.ConchRack != "":
+			finding.Status = auditMisplaced
+		default:
+			finding.Status = auditUnexpected
+		}
+
+		if finding.Status != auditMatch {
+			if _, ok := r.assignments[scan.RackUnitStart]; ok {
+				r.fix.Assign = append(r.fix.Assign, types.RackAssignmentUpdate{
+					DeviceSerialNumber: types.DeviceSerialNumber(scan.SerialNumber),
+					RackUnitStart:      types.PositiveInteger(scan.RackUnitStart),
+				})
+			} else {
+				if finding.Note != "" {
+					finding.Note += ", "
+				}
+				finding.Note += "no slot at this RU in the rack layout"
+			}
+		}
+		report = append(report, finding)
+	}
+
+	for _, name := range names {
+		r := racks[name]
+		for ru, a := range r.assignments {
+			if a.DeviceSerialNumber == "" {
+				continue
+			}
+			found := false
+			for _, scan := range scans {
+				if scan.Rack == name && scan.RackUnitStart == ru && scan.SerialNumber == string(a.DeviceSerialNumber) {
+					found = true
+					break
+				}
+			}
+			if found {
+				continue
+			}
+
+			r.fix.Delete = append(r.fix.Delete, types.RackAssignmentDelete{
+				DeviceID:      a.DeviceID,
+				RackUnitStart: a.RackUnitStart,
+			})
+			if !seen[string(a.DeviceSerialNumber)] {
+				report = append(report, auditFinding{
+					Status:             auditMissing,
+					SerialNumber:       string(a.DeviceSerialNumber),
+					ConchRack:          name,
+					ConchRackUnitStart: ru,
+				})
+			}
+		}
+	}
+
+	var plan auditPlan
+	for _, name := range append(names, outside...) {
+		fix := racks[name].fix
+		if len(fix.Delete) == 0 && len(fix.Assign) == 0 {
+			continue
+		}
+		sort.Slice(fix.Delete, func(i, j int) bool { return fix.Delete[i].RackUnitStart > fix.Delete[j].RackUnitStart })
+		plan = append(plan, fix)
+	}
+
+	sort.Sort(report)
+	return report, plan, nil
+}
+
+// applyAuditPlan removes the stale assignments from every rack before making
+// the new ones, so devices can move between the racks in the plan
+func applyAuditPlan(c *conch.Client, plan auditPlan) error {
+	for _, fix := range plan {
+		if len(fix.Delete) == 0 {
+			continue
+		}
+		if e := c.DeleteRackAssignments(fix.RackID, fix.Delete); e != nil {
+			return fmt.Errorf("%s: %w", fix.Rack, e)
+		}
+		fmt.Fprintf(os.Stderr, "%s: removed %d assignments\n", fix.Rack, len(fix.Delete))
+	}
+	for _, fix := range plan {
+		if len(fix.Assign) == 0 {
+			continue
+		}
+		if e := c.UpdateRackAssignments(fix.RackID, fix.Assign); e != nil {
+			return fmt.Errorf("%s: %w", fix.Rack, e)
+		}
+		fmt.Fprintf(os.Stderr, "%s: made %d assignments\n", fix.Rack, len(fix.Assign))
+	}
+	return nil
+}
+
+func auditCmd(cmd *cli.Cmd) {
+	var conch *conch.Client
+	var display func(interface{}, error)
+
+	cmd.Before = func() {
+		config.requireAuth()
+		conch = config.ConchClient()
+		display = config.Renderer()
+	}
+
+	cmd.Command("racks", "Compare a CSV of rack,ru,serial from the floor with the rack assignments in Conch", func(cmd *cli.Cmd) {
+		var (
+			planOpt     = cmd.StringOpt("plan", "", "Write the assignment changes that would make Conch match the floor to this file, for review")
+			applyOpt    = cmd.BoolOpt("apply", false, "Apply a plan written by --plan, given as FILE")
+			filePathArg = cmd.StringArg("FILE", "-", "Path to the CSV file, or the plan with --apply. '-' indicates STDIN")
+		)
+		cmd.Spec = "[--plan] [--apply] [FILE]"
+
+		cmd.Action = func() {
+			if *applyOpt && *planOpt != "" {
+				fatalIf(errUsage("--plan and --apply can't be used together"))
+			}
+
+			input, e := getInputReader(*filePathArg)
+			fatalIf(e)
+
+			if *applyOpt {
+				var plan auditPlan
+				if e := json.NewDecoder(input).Decode(&plan); e != nil {
+					fatalIf(fmt.Errorf("could not read the plan: %w", e))
+				}
+				fatalIf(applyAuditPlan(conch, plan))
+				fmt.Println("OK")
+				return
+			}
+
+			scans, e := readAuditScans(input)
+			fatalIf(e)
+			report, plan, e := auditRacks(conch, scans)
+			fatalIf(e)
+			display(report, nil)
+
+			if *planOpt == "" {
+				return
+			}
+			out, e := json.MarshalIndent(plan, "", "  ")
+			fatalIf(e)
+			fatalIf(ioutil.WriteFile(*planOpt, append(out, '\n'), 0600))
+			fmt.Fprintf(os.Stderr, "Wrote the plan for %d racks to %s, apply it with 'kosh audit racks --apply %s'\n", len(plan), *planOpt, *planOpt)
+		}
+	})
+}
@@ -0,0 +1,201 @@
+package cli
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/joyent/kosh/conch"
+	"github.com/joyent/kosh/conch/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadAuditScans(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Input    string
+		Expected []auditScan
+		Err      string
+	}{
+		{
+			Name:  "header and rows",
+			Input: "rack,ru,serial\nr1, 1, SN1\nr2,3,SN2\n",
+			Expected: []auditScan{
+				{Rack: "r1", RackUnitStart: 1, SerialNumber: "SN1"},
+				{Rack: "r2", RackUnitStart: 3, SerialNumber: "SN2"},
+			},
+		},
+		{
+			Name:     "empty",
+			Input:    "",
+			Expected: nil,
+		},
+		{
+			Name:  "not a rack unit",
+			Input: "r1,1,SN1\nr1,two,SN2\n",
+			Err:   `row 2: "two" is not a rack unit`,
+		},
+		{
+			Name:  "no serial",
+			Input: "r1,1,\n",
+			Err:   "row 1: expected rack,ru,serial",
+		},
+		{
+			Name:  "wrong number of columns",
+			Input: "r1,1,SN1\nr1,2\n",
+			Err:   "wrong number of fields",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			scans, err := readAuditScans(strings.NewReader(test.Input))
+			if test.Err != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), test.Err)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, test.Expected, scans)
+		})
+	}
+}
+
+// testAuditServer has the racks r1 and r2, which are scanned, and r9, which
+// isn't. SNX is in r9 and SNU is unknown.
+func testAuditServer(requests *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimSuffix(r.URL.Path, "/")
+		*requests = append(*requests, r.Method+" "+path)
+		w.Header().Set("Content-Type", "application/json")
+		switch path {
+		case "/rack/r1":
+			w.Write([]byte(`{"id": "00000000-0000-0000-0000-0000000000a1", "name": "r1"}`))
+		case "/rack/r2":
+			w.Write([]byte(`{"id": "00000000-0000-0000-0000-0000000000a2", "name": "r2"}`))
+		case "/rack/r9":
+			w.Write([]byte(`{"id": "00000000-0000-0000-0000-0000000000a9", "name": "r9"}`))
+		case "/rack/00000000-0000-0000-0000-0000000000a1/assignment":
+			if r.Method != http.MethodGet {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			w.Write([]byte(`[
+				{"rack_unit_start": 1, "device_id": "00000000-0000-0000-0000-000000000001", "device_serial_number": "SN1"},
+				{"rack_unit_start": 3, "device_id": "00000000-0000-0000-0000-000000000003", "device_serial_number": "SN3"},
+				{"rack_unit_start": 5},
+				{"rack_unit_start": 7, "device_id": "00000000-0000-0000-0000-000000000007", "device_serial_number": "SN7"}
+			]`))
+		case "/rack/00000000-0000-0000-0000-0000000000a2/assignment":
+			if r.Method != http.MethodGet {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			w.Write([]byte(`[
+				{"rack_unit_start": 1, "device_id": "00000000-0000-0000-0000-000000000021", "device_serial_number": "SN21"}
+			]`))
+		case "/rack/00000000-0000-0000-0000-0000000000a9/assignment":
+			w.WriteHeader(http.StatusNoContent)
+		case "/device/SNX":
+			w.Write([]byte(`{
+				"id": "00000000-0000-0000-0000-000000000099",
+				"location": {"rack": "r9", "rack_unit_start": 2}
+			}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "Not Found"}`))
+		}
+	}))
+}
+
+func TestAuditRacks(t *testing.T) {
+	var requests []string
+	ts := testAuditServer(&requests)
+	defer ts.Close()
+
+	scans := []auditScan{
+		{Rack: "r1", RackUnitStart: 1, SerialNumber: "SN1"},
+		{Rack: "r1", RackUnitStart: 3, SerialNumber: "SN21"},
+		{Rack: "r1", RackUnitStart: 5, SerialNumber: "SNX"},
+		{Rack: "r2", RackUnitStart: 1, SerialNumber: "SN3"},
+		{Rack: "r2", RackUnitStart: 4, SerialNumber: "SNU"},
+		{Rack: "r2", RackUnitStart: 2, SerialNumber: "SN1"},
+	}
+	report, plan, err := auditRacks(conch.New(conch.API(ts.URL)), scans)
+	assert.Nil(t, err)
+
+	assert.Equal(t, auditReport{
+		{Status: auditMatch, SerialNumber: "SN1", Rack: "r1", RackUnitStart: 1, ConchRack: "r1", ConchRackUnitStart: 1},
+		{Status: auditMisplaced, SerialNumber: "SNX", Rack: "r1", RackUnitStart: 5, ConchRack: "r9", ConchRackUnitStart: 2},
+		{Status: auditMisplaced, SerialNumber: "SN21", Rack: "r1", RackUnitStart: 3, ConchRack: "r2", ConchRackUnitStart: 1},
+		{Status: auditMisplaced, SerialNumber: "SN3", Rack: "r2", RackUnitStart: 1, ConchRack: "r1", ConchRackUnitStart: 3},
+		{Status: auditMissing, SerialNumber: "SN7", ConchRack: "r1", ConchRackUnitStart: 7},
+		{Status: auditUnexpected, SerialNumber: "SNU", Rack: "r2", RackUnitStart: 4, Note: "unknown to Conch, no slot at this RU in the rack layout"},
+		{Status: auditUnexpected, SerialNumber: "SN1", Rack: "r2", RackUnitStart: 2, Note: "scanned more than once"},
+	}, report)
+
+	assert.Equal(t, auditPlan{
+		{
+			Rack:   "r1",
+			RackID: testUUID("00000000-0000-0000-0000-0000000000a1"),
+			Delete: types.RackAssignmentDeletes{
+				{DeviceID: testUUID("00000000-0000-0000-0000-000000000007"), RackUnitStart: 7},
+				{DeviceID: testUUID("00000000-0000-0000-0000-000000000003"), RackUnitStart: 3},
+			},
+			Assign: types.RackAssignmentUpdates{
+				{DeviceSerialNumber: "SN21", RackUnitStart: 3},
+				{DeviceSerialNumber: "SNX", RackUnitStart: 5},
+			},
+		},
+		{
+			Rack:   "r2",
+			RackID: testUUID("00000000-0000-0000-0000-0000000000a2"),
+			Delete: types.RackAssignmentDeletes{
+				{DeviceID: testUUID("00000000-0000-0000-0000-000000000021"), RackUnitStart: 1},
+			},
+			Assign: types.RackAssignmentUpdates{
+				{DeviceSerialNumber: "SN3", RackUnitStart: 1},
+			},
+		},
+		{
+			// SNX has to come out of r9, which wasn't scanned, before it can
+			// go into r1
+			Rack:   "r9",
+			RackID: testUUID("00000000-0000-0000-0000-0000000000a9"),
+			Delete: types.RackAssignmentDeletes{
+				{DeviceID: testUUID("00000000-0000-0000-0000-000000000099"), RackUnitStart: 2},
+			},
+		},
+	}, plan)
+
+	// the plan is written as JSON for review and read back with --apply
+	out, err := json.Marshal(plan)
+	assert.Nil(t, err)
+	var read auditPlan
+	assert.Nil(t, json.Unmarshal(out, &read))
+	assert.Equal(t, plan, read)
+
+	// every delete is made before any assignment, so devices can move
+	// between racks
+	requests = nil
+	assert.Nil(t, applyAuditPlan(conch.New(conch.API(ts.URL)), plan))
+	assert.Equal(t, []string{
+		"DELETE /rack/00000000-0000-0000-0000-0000000000a1/assignment",
+		"DELETE /rack/00000000-0000-0000-0000-0000000000a2/assignment",
+		"DELETE /rack/00000000-0000-0000-0000-0000000000a9/assignment",
+		"POST /rack/00000000-0000-0000-0000-0000000000a1/assignment",
+		"POST /rack/00000000-0000-0000-0000-0000000000a2/assignment",
+	}, requests)
+}
+
+func TestAuditRacksUnknownRack(t *testing.T) {
+	var requests []string
+	ts := testAuditServer(&requests)
+	defer ts.Close()
+
+	_, _, err := auditRacks(conch.New(conch.API(ts.URL)), []auditScan{{Rack: "nope", RackUnitStart: 1, SerialNumber: "SN1"}})
+	assert.Error(t, err)
+}
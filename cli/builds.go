@@ -2,6 +2,7 @@ package cli
 
 import (
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
@@ -90,13 +91,13 @@ func buildsCmd(cmd *cli.Cmd) {
 
 		cmd.Spec = "NAME [OPTIONS]"
 		cmd.Action = func() {
-			conch.CreateBuild(
+			fatalIf(conch.CreateBuild(
 				types.BuildCreate{
 					Name:        types.MojoStandardPlaceholder(*nameArg),
 					Description: types.NonEmptyString(*descOpt),
 					Admins:      []types.Admin{types.Admin{Email: types.EmailAddress(*adminEmailArg)}},
 				},
-			)
+			))
 			getAllBuilds()
 		}
 	})
@@ -116,6 +117,11 @@ func buildCmd(cmd *cli.Cmd) {
 		conch = config.ConchClient()
 		display = config.Renderer()
 
+		if b, ok := config.shell.contextBuild(*buildNameArg); ok {
+			build = b
+			return
+		}
+
 		var e error
 		build, e = conch.GetBuildByName(*buildNameArg)
 		fatalIf(e)
@@ -180,19 +186,19 @@ func buildCmd(cmd *cli.Cmd) {
 			cmd.Spec = "EMAIL [OPTIONS]"
 			cmd.Action = func() {
 				if !okBuildRole(*roleOpt) {
-					fatalIf(fmt.Errorf(
+					fatalIf(errUsage(
 						"'role' value must be one of: %s",
 						prettyBuildRoleList(),
 					))
 				}
-				conch.AddBuildUser(
+				fatalIf(conch.AddBuildUser(
 					*buildNameArg,
 					types.BuildAddUser{
 						Email: types.EmailAddress(*userEmailArg),
 						Role:  types.Role(*roleOpt),
 					},
 					*sendEmailOpt,
-				)
+				))
 				display(conch.GetBuildUsers(*buildNameArg))
 			}
 		})
@@ -211,7 +217,7 @@ func buildCmd(cmd *cli.Cmd) {
 			)
 			cmd.Spec = "EMAIL [OPTIONS]"
 			cmd.Action = func() {
-				conch.DeleteBuildUser(*buildNameArg, *userEmailArg, *sendEmailOpt)
+				fatalIf(conch.DeleteBuildUser(*buildNameArg, *userEmailArg, *sendEmailOpt))
 				display(conch.GetBuildUsers(*buildNameArg))
 			}
 		})
@@ -246,7 +252,7 @@ func buildCmd(cmd *cli.Cmd) {
 			cmd.Spec = "NAME [OPTIONS]"
 			cmd.Action = func() {
 				if !okBuildRole(*roleOpt) {
-					fatalIf(fmt.Errorf(
+					fatalIf(errUsage(
 						"'role' value must be one of: %s",
 						prettyBuildRoleList(),
 					))
@@ -254,12 +260,12 @@ func buildCmd(cmd *cli.Cmd) {
 				org, e := conch.GetOrganizationByName(*orgNameArg)
 				fatalIf(e)
 
-				conch.AddBuildOrganization(*buildNameArg, types.BuildAddOrganization{
-					org.ID,
-					types.Role(*roleOpt),
+				fatalIf(conch.AddBuildOrganization(*buildNameArg, types.BuildAddOrganization{
+					OrganizationID: org.ID,
+					Role:           types.Role(*roleOpt),
 				},
 					*sendEmailOpt,
-				)
+				))
 				display(conch.GetAllBuildOrganizations(*buildNameArg))
 			}
 		})
@@ -278,15 +284,34 @@ func buildCmd(cmd *cli.Cmd) {
 			)
 			cmd.Spec = "EMAIL [OPTIONS]"
 			cmd.Action = func() {
-				conch.DeleteBuildOrganization(*buildNameArg,
+				fatalIf(conch.DeleteBuildOrganization(*buildNameArg,
 					*orgNameArg,
 					*sendEmailOpt,
-				)
+				))
 				display(conch.GetAllBuildOrganizations(*buildNameArg))
 			}
 		})
 	})
 
+	cmd.Command("conform", "Compare the latest report of every device in the build with the hardware its product should have", func(cmd *cli.Cmd) {
+		parallelOpt := cmd.IntOpt("parallel", 4, "How many devices to check at once")
+		cmd.Spec = "[--parallel]"
+
+		cmd.Action = func() {
+			devices, e := conch.GetAllBuildDevices(*buildNameArg)
+			fatalIf(e)
+
+			mismatches := conformDevices(conch, devices, *parallelOpt)
+			display(mismatches, nil)
+
+			n := mismatches.devices()
+			fmt.Fprintf(os.Stderr, "%d of %d devices conform\n", len(devices)-n, len(devices))
+			if n > 0 {
+				fatalIf(fmt.Errorf("%d devices in build %s do not conform", n, build.Name))
+			}
+		}
+	})
+
 	cmd.Command("devices ds", "Manage devices in a specific build", func(cmd *cli.Cmd) {
 		// list by default
 		cmd.Action = func() { display(conch.GetAllBuildDevices(*buildNameArg)) }
@@ -304,7 +329,7 @@ func buildCmd(cmd *cli.Cmd) {
 
 			cmd.Spec = "ID [OPTIONS]"
 			cmd.Action = func() {
-				conch.AddBuildDeviceByName(*buildNameArg, *deviceIDArg)
+				fatalIf(conch.AddBuildDeviceByName(*buildNameArg, *deviceIDArg))
 				display(conch.GetAllBuildDevices(*buildNameArg))
 			}
 		})
@@ -324,7 +349,7 @@ func buildCmd(cmd *cli.Cmd) {
 				d, e := conch.GetDeviceBySerial(*deviceIDArg)
 				fatalIf(e)
 
-				conch.DeleteBuildDeviceByID(b.ID, d.ID)
+				fatalIf(conch.DeleteBuildDeviceByID(b.ID, d.ID))
 				display(conch.GetAllBuildDevices(*buildNameArg))
 			}
 		})
@@ -347,7 +372,7 @@ func buildCmd(cmd *cli.Cmd) {
 
 			cmd.Spec = "ID [OPTIONS]"
 			cmd.Action = func() {
-				conch.AddBuildRackByID(*buildNameArg, *rackIDArg)
+				fatalIf(conch.AddBuildRackByID(*buildNameArg, *rackIDArg))
 				display(conch.GetBuildRacks(*buildNameArg))
 			}
 		})
@@ -361,7 +386,7 @@ func buildCmd(cmd *cli.Cmd) {
 
 			cmd.Spec = "ID [OPTIONS]"
 			cmd.Action = func() {
-				conch.DeleteBuildRackByID(*buildNameArg, *rackIDArg)
+				fatalIf(conch.DeleteBuildRackByID(*buildNameArg, *rackIDArg))
 				display(conch.GetBuildRacks(*buildNameArg))
 			}
 		})
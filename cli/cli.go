@@ -1,12 +1,19 @@
 package cli
 
 import (
-	"errors"
+	"context"
+	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"os/signal"
+	"strings"
+	"time"
 
 	cli "github.com/jawher/mow.cli"
+	"github.com/joyent/kosh/conch"
+	"github.com/joyent/kosh/filter"
 )
 
 const (
@@ -14,13 +21,6 @@ const (
 	stagingURL    = "https://staging.conch.joyent.us"
 )
 
-func fatalIf(e error) {
-	if e != nil {
-		fmt.Println(e)
-		cli.Exit(1)
-	}
-}
-
 func getInputReader(filePathArg string) (io.Reader, error) {
 	if filePathArg == "-" {
 		return os.Stdin, nil
@@ -30,17 +30,67 @@ func getInputReader(filePathArg string) (io.Reader, error) {
 
 var config Config
 
-func (c Config) requireAuth() {
-	if c.ConchToken == "" {
-		fmt.Println("Need to provide --token or set KOSH_TOKEN")
-		cli.Exit(1)
+// envURL returns the API URL for one of the well known Conch environments
+func envURL(env string) (string, error) {
+	switch env {
+	case "production":
+		return productionURL, nil
+	case "staging":
+		return stagingURL, nil
+	default:
+		return "", errUsage("environment not one of production, staging: perhaps you want --url or --profile?")
+	}
+}
+
+// newContext returns the context used for every API call made by a single
+// kosh invocation. It is cancelled on an interrupt (Ctrl-C) and, if the
+// timeout is non-zero, once the timeout has elapsed. The cancel func must be
+// called once the invocation is done, to stop the timer and the interrupt
+// handler.
+func newContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	go func() {
+		// after the first interrupt, a second gets the default behavior and
+		// kills us outright
+		defer signal.Stop(sigs)
+		select {
+		case <-sigs:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// requireAuth stops the command if there are no credentials. It has a
+// pointer receiver because commands take it as a method value in their
+// definitions (cmd.Before = config.requireAuth), which run before app.Before
+// has applied the profile, and a value receiver would check that early copy.
+func (c *Config) requireAuth() {
+	if c.ConchToken == "" && c.TokenCommand == "" {
+		fatalIf(authError{"Need to provide --token or --token-command, set KOSH_TOKEN or run kosh login"})
 	}
 }
 
-func (c Config) requireSysAdmin() {
-	if !c.ConchClient().IsSysAdmin() {
-		fmt.Println("This action requires Conch systems administrator privileges")
-		cli.Exit(1)
+// requireSysAdmin stops the command unless the user is a Conch systems
+// administrator, with a pointer receiver for the same reason as requireAuth
+func (c *Config) requireSysAdmin() {
+	me, e := c.ConchClient().GetCurrentUser()
+	fatalIf(e)
+	if !me.IsAdmin {
+		fatalIf(authError{"This action requires Conch systems administrator privileges"})
 	}
 }
 
@@ -49,7 +99,11 @@ func NewApp(c Config) *cli.Cli {
 	config = c
 
 	app := cli.App("kosh", "Command line interface for Conch")
-	app.Spec = "[-dejutvV]"
+	if config.shell != nil {
+		// a mistyped command shouldn't end the kosh shell session
+		app.ErrorHandling = flag.ContinueOnError
+	}
+	app.Spec = "[-dejoptuvV] [--token-command] [--filter] [--template | --template-file] [--columns] [--sort-by] [--reverse] [--no-headers] [--wide] [--timeout] [--retries] [--dry-run]"
 
 	app.Version("V version", config.Version)
 
@@ -58,13 +112,26 @@ func NewApp(c Config) *cli.Cli {
 		Value:  "",
 		Desc:   "API token",
 		EnvVar: "KOSH_TOKEN CONCH_TOKEN",
+		// kosh shell fills this in before each line, keep it out of the help
+		HideValue: true,
+	})
+
+	var envSetByUser bool
+	app.StringPtr(&config.TokenCommand, cli.StringOpt{
+		Name:   "token-command",
+		Value:  "",
+		Desc:   "Command that prints an API token, run when no token is given",
+		EnvVar: "KOSH_TOKEN_COMMAND",
+		// the command line may hold a credential of its own
+		HideValue: true,
 	})
 
 	app.StringPtr(&config.ConchENV, cli.StringOpt{
-		Name:   "env e",
-		Value:  "production",
-		Desc:   "This specifies the environment KOSH is pointing to",
-		EnvVar: "KOSH_ENV CONCH_ENV",
+		Name:      "env e",
+		Value:     "production",
+		Desc:      "This specifies the environment KOSH is pointing to",
+		EnvVar:    "KOSH_ENV CONCH_ENV",
+		SetByUser: &envSetByUser,
 	})
 
 	app.StringPtr(&config.ConchURL, cli.StringOpt{
@@ -77,10 +144,100 @@ func NewApp(c Config) *cli.Cli {
 	app.BoolPtr(&config.OutputJSON, cli.BoolOpt{
 		Name:   "j json",
 		Value:  false,
-		Desc:   "Output JSON only",
+		Desc:   "Output JSON only, shorthand for --output json",
 		EnvVar: "KOSH_JSON_ONLY",
 	})
 
+	app.StringPtr(&config.Output, cli.StringOpt{
+		Name:   "o output",
+		Value:  "",
+		Desc:   "Output format. One of: " + strings.Join(outputList, ", "),
+		EnvVar: "KOSH_OUTPUT",
+	})
+
+	app.StringPtr(&config.Profile, cli.StringOpt{
+		Name:   "p profile",
+		Value:  "",
+		Desc:   "Use the named profile from the config file",
+		EnvVar: "KOSH_PROFILE",
+	})
+
+	filterOpt := app.String(cli.StringOpt{
+		Name:   "filter",
+		Value:  "",
+		Desc:   `Only show list items matching this expression, e.g. 'phase == "integration" && hostname =~ "^sw-"'`,
+		EnvVar: "KOSH_FILTER",
+	})
+
+	app.StringPtr(&config.Template, cli.StringOpt{
+		Name:   "template",
+		Value:  "",
+		Desc:   "Render output with this Go template, e.g. '{{ range . }}{{ .SerialNumber }}{{ \"\\n\" }}{{ end }}'",
+		EnvVar: "KOSH_TEMPLATE",
+	})
+
+	templateFileOpt := app.String(cli.StringOpt{
+		Name:   "template-file",
+		Value:  "",
+		Desc:   "Render output with the Go template in this file",
+		EnvVar: "KOSH_TEMPLATE_FILE",
+	})
+
+	columnsOpt := app.String(cli.StringOpt{
+		Name:   "columns",
+		Value:  "",
+		Desc:   "Comma separated list of the table columns to show, e.g. name,phase,health",
+		EnvVar: "KOSH_COLUMNS",
+	})
+
+	app.StringPtr(&config.TableOptions.SortBy, cli.StringOpt{
+		Name:   "sort-by",
+		Value:  "",
+		Desc:   "Sort table rows by this column",
+		EnvVar: "KOSH_SORT_BY",
+	})
+
+	app.BoolPtr(&config.TableOptions.Reverse, cli.BoolOpt{
+		Name:  "reverse",
+		Value: false,
+		Desc:  "Reverse the order of table rows",
+	})
+
+	app.BoolPtr(&config.TableOptions.NoHeaders, cli.BoolOpt{
+		Name:   "no-headers",
+		Value:  false,
+		Desc:   "Leave out the table header row",
+		EnvVar: "KOSH_NO_HEADERS",
+	})
+
+	app.BoolPtr(&config.TableOptions.Wide, cli.BoolOpt{
+		Name:   "wide",
+		Value:  false,
+		Desc:   "Show full UUIDs and extra columns in tables",
+		EnvVar: "KOSH_WIDE",
+	})
+
+	timeoutOpt := app.String(cli.StringOpt{
+		Name:   "timeout",
+		Value:  "",
+		Desc:   "Abort if talking to the API takes longer than this (e.g. 30s, 2m)",
+		EnvVar: "KOSH_TIMEOUT",
+	})
+
+	app.IntPtr(&config.Retries, cli.IntOpt{
+		Name:   "retries",
+		Value:  3,
+		Desc:   "Retry requests that fail with a transient error this many times",
+		EnvVar: "KOSH_RETRIES",
+	})
+
+	app.BoolPtr(&config.DryRun, cli.BoolOpt{
+		Name:   "dry-run",
+		Value:  false,
+		Desc:   "Show the requests that would change data instead of sending them",
+		EnvVar: "KOSH_DRY_RUN",
+	})
+
 	app.BoolPtr(&config.Logger.LevelDebug, cli.BoolOpt{
 		Name:   "d debug",
 		Value:  false,
@@ -96,16 +253,21 @@ func NewApp(c Config) *cli.Cli {
 	})
 
 	app.Command("admin", "System Administration Commands", adminCmd)
+	app.Command("audit", "Compare what is on the datacenter floor with Conch", auditCmd)
 	app.Command("build b", "Work with a specific build", buildCmd)
 	app.Command("builds bs", "Work with builds", buildsCmd)
+	app.Command("completion", "Print a shell completion script", completionCmd)
 	app.Command("datacenter dc", "Deal with a single datacenter", datacenterCmd)
 	app.Command("datacenters dcs", "Work with the datacenters you have access to", datacentersCmd)
 	app.Command("device d", "Perform actions against a single device", deviceCmd)
 	app.Command("device-report dr", "Deal with device reports", deviceReportCmd)
 	app.Command("devices ds", "Commands for dealing with multiple devices", devicesCmd)
 	app.Command("hardware h", "Work with hardware profiles and vendors", hardwareCmd)
+	app.Command("login", "Log in and save a new API token to the current profile", loginCmd)
+	app.Command("logout", "Delete the current profile's API token", logoutCmd)
 	app.Command("organization org", "Work with a specific organization", organizationCmd)
 	app.Command("organizations orgs", "Work with organizations", organizationsCmd)
+	app.Command("profile", "Manage the profiles in the kosh config file", profileCommandsCmd)
 	app.Command("rack r", "Work with a single rack", rackCmd)
 	app.Command("racks rs", "Work with datacenter racks", racksCmd)
 	app.Command("relay", "Perform actions against a single relay", relayCmd)
@@ -114,6 +276,7 @@ func NewApp(c Config) *cli.Cli {
 	app.Command("role", "Work with a single rack role", roleCmd)
 	app.Command("room", "Deal with a single datacenter room", roomCmd)
 	app.Command("rooms", "Work with datacenter rooms", roomsCmd)
+	app.Command("shell", "Start an interactive kosh shell", shellCmd)
 	app.Command("schema", "Get the server JSON Schema for a given request or response", schemaCmd)
 	app.Command("user u", "Commands for dealing with the current user (you)", userCmd)
 	app.Command("update", "commands for updating kosh", updateCmd)
@@ -134,19 +297,96 @@ func NewApp(c Config) *cli.Cli {
 	})
 
 	app.Before = func() {
+		if config.ConfigFile == "" {
+			config.ConfigFile = DefaultConfigFilePath()
+		}
+
+		// an explicitly chosen environment wins over the current profile,
+		// mow.cli doesn't count values from the environment as set by user
+		envSet := envSetByUser || os.Getenv("KOSH_ENV") != "" || os.Getenv("CONCH_ENV") != ""
+		if config.ConchURL == "" && envSet {
+			var e error
+			config.ConchURL, e = envURL(config.ConchENV)
+			fatalIf(e)
+		}
+
+		// the current profile is only a default, a profile named with
+		// --profile must exist
+		if config.Profile != "" || config.ConchURL == "" {
+			file, e := LoadConfigFile(config.ConfigFile)
+			fatalIf(e)
+			if config.Profile != "" {
+				profile, e := file.Profile(config.Profile)
+				fatalIf(e)
+				config.ApplyProfile(profile)
+			} else if profile, ok := file.Profiles[file.Current]; ok {
+				config.Profile = file.Current
+				config.ApplyProfile(profile)
+			}
+		}
+
 		if config.ConchURL == "" {
-			switch config.ConchENV {
-			case "production":
-				config.ConchURL = productionURL
-			case "staging":
-				config.ConchURL = stagingURL
-			default:
-				fatalIf(errors.New("environment not one of production, staging, edge: perhaps you want --url?"))
+			var e error
+			config.ConchURL, e = envURL(config.ConchENV)
+			fatalIf(e)
+		}
+
+		if *timeoutOpt != "" {
+			timeout, e := time.ParseDuration(*timeoutOpt)
+			if e != nil {
+				fatalIf(errUsage("--timeout is not a valid duration: %s", e))
+			}
+			config.Timeout = timeout
+		}
+		config.Context, config.cancel = newContext(config.Timeout)
+
+		if !okOutput(config.Output) {
+			fatalIf(errUsage("--output must be one of: %s", strings.Join(outputList, ", ")))
+		}
+
+		if *columnsOpt != "" {
+			for _, column := range strings.Split(*columnsOpt, ",") {
+				if column = strings.TrimSpace(column); column != "" {
+					config.TableOptions.Columns = append(config.TableOptions.Columns, column)
+				}
 			}
 		}
+		config.TableOptions.FullUUIDs = config.TableOptions.Wide
+
+		if *filterOpt != "" {
+			f, e := filter.Parse(*filterOpt)
+			fatalIf(e)
+			config.Filter = f
+		}
+
+		if *templateFileOpt != "" {
+			b, e := ioutil.ReadFile(*templateFileOpt)
+			fatalIf(e)
+			config.Template = string(b)
+		}
+
+		// kosh shell keeps the helper, and the token it has, between commands
+		if config.TokenCommand != "" && config.tokenHelper == nil {
+			config.tokenHelper = NewTokenHelper(config.TokenCommand)
+		}
+
+		// the requests go to stderr, so they don't get mixed up with JSON
+		// or CSV output
+		if config.DryRun {
+			config.dryRunLog = conch.NewDryRunLog(os.Stderr)
+		}
 
 		config.Debug(config)
 	}
 
+	app.After = func() {
+		if config.cancel != nil {
+			config.cancel()
+		}
+		if config.dryRunLog != nil {
+			fmt.Fprintln(os.Stderr, config.dryRunLog.Summary())
+		}
+	}
+
 	return app
 }
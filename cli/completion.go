@@ -0,0 +1,445 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	cli "github.com/jawher/mow.cli"
+)
+
+// completionValuesTTL is how long resource names fetched from the API for
+// completion are reused before they're fetched again
+const completionValuesTTL = 5 * time.Minute
+
+// completionArgs maps a command path and argument name to the kind of
+// resource the argument names, for completing it from the API
+var completionArgs = map[string]string{
+	"build NAME":               "builds",
+	"room ALIAS":               "rooms",
+	"role NAME":                "roles",
+	"hardware product PRODUCT": "products",
+	"validation plan UUID":     "plans",
+	"profile show NAME":        "profiles",
+	"profile use NAME":         "profiles",
+	"profile remove NAME":      "profiles",
+}
+
+// completionValues fetches the names of each kind of resource
+var completionValues = map[string]func() ([]string, error){
+	"builds": func() (names []string, e error) {
+		builds, e := config.ConchClient().GetAllBuilds()
+		for _, b := range builds {
+			names = append(names, string(b.Name))
+		}
+		return names, e
+	},
+	"rooms": func() (names []string, e error) {
+		rooms, e := config.ConchClient().GetAllRooms()
+		for _, r := range rooms {
+			names = append(names, string(r.Alias))
+		}
+		return names, e
+	},
+	"roles": func() (names []string, e error) {
+		roles, e := config.ConchClient().GetAllRackRoles()
+		for _, r := range roles {
+			names = append(names, string(r.Name))
+		}
+		return names, e
+	},
+	"products": func() (names []string, e error) {
+		products, e := config.ConchClient().GetHardwareProducts()
+		for _, p := range products {
+			names = append(names, string(p.SKU))
+		}
+		return names, e
+	},
+	"plans": func() (names []string, e error) {
+		plans, e := config.ConchClient().GetAllValidationPlans()
+		for _, p := range plans {
+			names = append(names, string(p.Name))
+		}
+		return names, e
+	},
+}
+
+// completionOptionValues completes the values of global options
+var completionOptionValues = map[string]func() []string{
+	"-o":        func() []string { return outputList },
+	"--output":  func() []string { return outputList },
+	"-e":        func() []string { return []string{"production", "staging"} },
+	"--env":     func() []string { return []string{"production", "staging"} },
+	"-p":        profileNames,
+	"--profile": profileNames,
+}
+
+func profileNames() []string {
+	file, e := LoadConfigFile(config.ConfigFile)
+	if e != nil {
+		return nil
+	}
+	var names []string
+	for name := range file.Profiles {
+		names = append(names, name)
+	}
+	return names
+}
+
+// completionNode is a single command as described by its --help output
+type completionNode struct {
+	Commands [][]string `json:"commands"` // the names and aliases of each sub command
+	Args     []string   `json:"args"`
+	Options  []string   `json:"options"`
+}
+
+// subcommand returns the canonical name of the sub command with the given
+// name or alias
+func (n completionNode) subcommand(word string) (string, bool) {
+	for _, names := range n.Commands {
+		for _, name := range names {
+			if name == word {
+				return names[0], true
+			}
+		}
+	}
+	return "", false
+}
+
+// completionTree caches the help of each command, keyed by the command path.
+// mow.cli doesn't expose the command tree, it only builds each part of it as
+// it's parsed, so kosh runs itself with --help to find out what a command
+// takes and remembers the answer for as long as the binary stays the same.
+type completionTree struct {
+	Binary string                    `json:"binary"`
+	Nodes  map[string]completionNode `json:"nodes"`
+
+	path  string
+	dirty bool
+}
+
+func completionCacheDir() string {
+	dir, e := os.UserCacheDir()
+	if e != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "kosh")
+}
+
+// binaryID identifies the running kosh binary, so the cached tree is thrown
+// away when kosh is upgraded
+func binaryID() string {
+	exe, e := os.Executable()
+	if e != nil {
+		return ""
+	}
+	info, e := os.Stat(exe)
+	if e != nil {
+		return exe
+	}
+	return fmt.Sprintf("%s %d %d", exe, info.Size(), info.ModTime().Unix())
+}
+
+func loadCompletionTree() *completionTree {
+	t := &completionTree{path: filepath.Join(completionCacheDir(), "completion-tree.json")}
+	if b, e := ioutil.ReadFile(t.path); e == nil {
+		json.Unmarshal(b, t)
+	}
+	if id := binaryID(); t.Binary != id || t.Nodes == nil {
+		t.Binary = id
+		t.Nodes = map[string]completionNode{}
+	}
+	return t
+}
+
+func (t *completionTree) save() {
+	if !t.dirty {
+		return
+	}
+	b, e := json.Marshal(t)
+	if e != nil {
+		return
+	}
+	os.MkdirAll(filepath.Dir(t.path), 0700)
+	ioutil.WriteFile(t.path, b, 0600)
+}
+
+// node returns the help for the command at the given path. Arguments in the
+// path are given as their names (e.g. "build NAME users") and are replaced
+// with a placeholder when running kosh.
+func (t *completionTree) node(path []string, args []bool) completionNode {
+	key := strings.Join(path, " ")
+	if n, ok := t.Nodes[key]; ok {
+		return n
+	}
+
+	argv := make([]string, 0, len(path)+1)
+	for i, word := range path {
+		if args[i] {
+			word = "x"
+		}
+		argv = append(argv, word)
+	}
+	argv = append(argv, "--help")
+
+	exe, e := os.Executable()
+	if e != nil {
+		return completionNode{}
+	}
+	// help exits non-zero when required arguments are missing, the output
+	// is the same either way
+	out, _ := exec.Command(exe, argv...).CombinedOutput()
+
+	n := parseHelp(out)
+	t.Nodes[key] = n
+	t.dirty = true
+	return n
+}
+
+// parseHelp reads the Arguments, Options and Commands sections of mow.cli's
+// help output
+func parseHelp(help []byte) completionNode {
+	n := completionNode{}
+	section := ""
+
+	scanner := bufio.NewScanner(bytes.NewReader(help))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			continue
+		case !strings.HasPrefix(line, " "):
+			section = trimmed
+			continue
+		}
+
+		// the name column ends at the first run of two spaces
+		name := trimmed
+		if i := strings.Index(trimmed, "  "); i >= 0 {
+			name = trimmed[:i]
+		}
+
+		switch section {
+		case "Arguments:":
+			n.Args = append(n.Args, name)
+		case "Options:":
+			for _, opt := range strings.Split(name, ",") {
+				n.Options = append(n.Options, strings.TrimSpace(opt))
+			}
+		case "Commands:":
+			var names []string
+			for _, cmd := range strings.Split(name, ",") {
+				names = append(names, strings.TrimSpace(cmd))
+			}
+			n.Commands = append(n.Commands, names)
+		}
+	}
+	return n
+}
+
+// complete returns the candidates for the last of the words, given the words
+// before it on the command line (not including "kosh" itself)
+func complete(tree *completionTree, words []string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	current := words[len(words)-1]
+
+	var (
+		path    []string
+		args    []bool
+		pending []string
+		node    = tree.node(nil, nil)
+		prev    string
+	)
+
+	for _, word := range words[:len(words)-1] {
+		switch {
+		case strings.HasPrefix(word, "-"):
+		case len(pending) > 0:
+			path = append(path, pending[0])
+			args = append(args, true)
+			pending = pending[1:]
+		default:
+			name, ok := node.subcommand(word)
+			if !ok {
+				// most likely the value of an option
+				if strings.HasPrefix(prev, "-") && !strings.Contains(prev, "=") {
+					break
+				}
+				return nil
+			}
+			path = append(path, name)
+			args = append(args, false)
+			node = tree.node(path, args)
+			pending = node.Args
+		}
+		prev = word
+	}
+
+	var candidates []string
+	switch {
+	case strings.HasPrefix(current, "-"):
+		// global options can be given anywhere, but are only in the top
+		// level help
+		options := node.Options
+		if len(path) > 0 {
+			options = append(options, tree.node(nil, nil).Options...)
+		}
+		for _, opt := range options {
+			if strings.HasPrefix(opt, "--") || current == "-" {
+				candidates = append(candidates, opt)
+			}
+		}
+
+	case completionOptionValues[prev] != nil:
+		candidates = completionOptionValues[prev]()
+
+	case len(pending) > 0:
+		kind := completionArgs[strings.Join(append(path, pending[0]), " ")]
+		candidates = completionValuesFor(kind)
+
+	default:
+		for _, names := range node.Commands {
+			candidates = append(candidates, names[0])
+		}
+	}
+
+	var matches []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, current) {
+			matches = append(matches, c)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+type cachedValues struct {
+	Fetched time.Time `json:"fetched"`
+	Values  []string  `json:"values"`
+}
+
+// completionValuesFor returns the names of a kind of resource, from the
+// cache if they were fetched recently enough
+func completionValuesFor(kind string) []string {
+	switch kind {
+	case "":
+		return nil
+	case "profiles":
+		return profileNames()
+	}
+
+	fetch := completionValues[kind]
+	if fetch == nil || (config.ConchToken == "" && config.TokenCommand == "") {
+		return nil
+	}
+
+	// resource names differ between Conch instances
+	sum := sha1.Sum([]byte(config.ConchURL))
+	path := filepath.Join(
+		completionCacheDir(),
+		fmt.Sprintf("completion-%x-%s.json", sum[:6], kind),
+	)
+
+	var cached cachedValues
+	if b, e := ioutil.ReadFile(path); e == nil {
+		if json.Unmarshal(b, &cached) == nil && time.Since(cached.Fetched) < completionValuesTTL {
+			return cached.Values
+		}
+	}
+
+	values, e := fetch()
+	if e != nil {
+		return cached.Values
+	}
+
+	cached = cachedValues{Fetched: time.Now(), Values: values}
+	if b, e := json.Marshal(cached); e == nil {
+		os.MkdirAll(filepath.Dir(path), 0700)
+		ioutil.WriteFile(path, b, 0600)
+	}
+	return values
+}
+
+const bashCompletion = `# bash completion for kosh
+#
+# Load it for the current shell with: source <(kosh completion bash)
+
+_kosh() {
+    local IFS=$'\n'
+    COMPREPLY=($(kosh completion complete -- "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _kosh kosh
+`
+
+const zshCompletion = `#compdef kosh
+#
+# zsh completion for kosh
+#
+# Load it for the current shell with: source <(kosh completion zsh)
+
+_kosh() {
+    local -a candidates
+    candidates=(${(f)"$(kosh completion complete -- "${(@)words[2,$CURRENT]}" 2>/dev/null)"})
+    compadd -a candidates
+}
+
+if [ "$funcstack[1]" = "_kosh" ]; then
+    _kosh "$@"
+else
+    compdef _kosh kosh
+fi
+`
+
+const fishCompletion = `# fish completion for kosh
+#
+# Load it for the current shell with: kosh completion fish | source
+
+function __kosh_complete
+    set -l words (commandline -opc) (commandline -ct)
+    kosh completion complete -- $words[2..-1] 2>/dev/null
+end
+
+complete -c kosh -f -a '(__kosh_complete)'
+`
+
+func completionCmd(cmd *cli.Cmd) {
+	cmd.LongDesc = `Print a completion script for bash, zsh or fish. Commands and options come
+from kosh itself, build names, room aliases, rack roles, hardware product SKUs
+and validation plans are looked up in the API and cached for a few minutes.`
+
+	scripts := []struct{ shell, script string }{
+		{"bash", bashCompletion},
+		{"zsh", zshCompletion},
+		{"fish", fishCompletion},
+	}
+	for _, s := range scripts {
+		script := s.script
+		cmd.Command(s.shell, "Print the completion script for "+s.shell, func(cmd *cli.Cmd) {
+			cmd.Action = func() { fmt.Print(script) }
+		})
+	}
+
+	cmd.Command("complete", "Print the completions for a partial command line, used by the completion scripts", func(cmd *cli.Cmd) {
+		wordsArg := cmd.StringsArg("WORDS", nil, "The words on the command line after 'kosh', the last one is being completed")
+		cmd.Spec = "[WORDS...]"
+		cmd.Action = func() {
+			tree := loadCompletionTree()
+			for _, c := range complete(tree, *wordsArg) {
+				fmt.Println(c)
+			}
+			tree.save()
+		}
+	})
+}
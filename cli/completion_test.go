@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const buildHelp = `
+Usage: kosh build NAME COMMAND [arg...]
+
+Work with a specific build
+
+Arguments:
+  NAME                  Name or ID of the build
+
+Options:
+  -f, --force           Do it anyway
+
+Commands:
+  get                   Get information about a single build by its name
+  organizations, orgs   Manage organizations in a specific build
+  devices, ds           Manage devices in a specific build
+
+Run 'kosh build COMMAND --help' for more information on a command.
+`
+
+func TestParseHelp(t *testing.T) {
+	assert.Equal(t, completionNode{
+		Commands: [][]string{{"get"}, {"organizations", "orgs"}, {"devices", "ds"}},
+		Args:     []string{"NAME"},
+		Options:  []string{"-f", "--force"},
+	}, parseHelp([]byte(buildHelp)))
+
+	assert.Equal(t, completionNode{}, parseHelp(nil))
+}
+
+// testCompletionTree is a tree that never runs kosh, every node it needs is
+// already cached. Like mow.cli's help, a command with arguments lists the sub
+// commands that come after them.
+func testCompletionTree() *completionTree {
+	return &completionTree{Nodes: map[string]completionNode{
+		"": {
+			Commands: [][]string{{"build", "b"}, {"builds", "bs"}, {"completion"}},
+			Options:  []string{"-o", "--output", "-v", "--verbose"},
+		},
+		"build": parseHelp([]byte(buildHelp)),
+		"build NAME devices": {
+			Commands: [][]string{{"get"}},
+			Options:  []string{"--phase"},
+		},
+		"completion": {Commands: [][]string{{"bash"}, {"zsh"}, {"fish"}, {"complete"}}},
+	}}
+}
+
+func TestComplete(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Words    []string
+		Expected []string
+	}{
+		{"nothing", nil, []string{"build", "builds", "completion"}},
+		{"command prefix", []string{"bu"}, []string{"build", "builds"}},
+		{"alias", []string{"b", "x", "or"}, []string{"organizations"}},
+		{"sub command", []string{"completion", "z"}, []string{"zsh"}},
+		{"argument", []string{"build", ""}, nil},
+		{"after an argument", []string{"build", "x", "d"}, []string{"devices"}},
+		{"nested", []string{"build", "x", "ds", ""}, []string{"get"}},
+		{"long options", []string{"build", "x", "--"}, []string{"--force", "--output", "--verbose"}},
+		{"all options", []string{"-"}, []string{"--output", "--verbose", "-o", "-v"}},
+		{"option value", []string{"-o", "j"}, []string{"json", "json-pretty", "jsonl"}},
+		{"after an option value", []string{"-o", "json", "comp"}, []string{"completion"}},
+		{"unknown command", []string{"nope", ""}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Expected, complete(testCompletionTree(), test.Words))
+		})
+	}
+}
+
+func TestCompletionTree(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kosh-completion")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	oldCache, hadCache := os.LookupEnv("XDG_CACHE_HOME")
+	os.Setenv("XDG_CACHE_HOME", dir)
+	defer func() {
+		if hadCache {
+			os.Setenv("XDG_CACHE_HOME", oldCache)
+		} else {
+			os.Unsetenv("XDG_CACHE_HOME")
+		}
+	}()
+
+	tree := loadCompletionTree()
+	assert.Equal(t, binaryID(), tree.Binary)
+	assert.Empty(t, tree.Nodes)
+
+	// nothing is written until a node has been looked up
+	tree.save()
+	_, err = os.Stat(tree.path)
+	assert.True(t, os.IsNotExist(err))
+
+	tree.Nodes["build"] = completionNode{Args: []string{"NAME"}}
+	tree.dirty = true
+	tree.save()
+
+	info, err := os.Stat(filepath.Join(dir, "kosh", "completion-tree.json"))
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	loaded := loadCompletionTree()
+	assert.Equal(t, completionNode{Args: []string{"NAME"}}, loaded.node([]string{"build"}, []bool{false}))
+
+	// a tree from another kosh binary is thrown away
+	loaded.Binary = "some other kosh"
+	loaded.dirty = true
+	loaded.save()
+	assert.Empty(t, loadCompletionTree().Nodes)
+}
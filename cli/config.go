@@ -1,14 +1,16 @@
 package cli
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/joyent/kosh/conch"
+	"github.com/joyent/kosh/filter"
 	"github.com/joyent/kosh/logger"
 	"github.com/joyent/kosh/tables"
 	"github.com/joyent/kosh/template"
@@ -21,9 +23,43 @@ type Config struct {
 
 	ConchURL   string
 	ConchToken string
-	ConchENV   string
+	// TokenCommand is run to get an API token when ConchToken isn't set
+	TokenCommand string
+	ConchENV     string
+
+	// Profile is the name of the config file profile in use, if any
+	Profile string
+	// ConfigFile is the path of the config file holding the profiles
+	ConfigFile string
 
 	OutputJSON bool
+	// Output is the output format, one of the values in outputList
+	Output string
+	// Filter, if set, narrows down lists before they're rendered
+	Filter *filter.Filter
+	// Template is a Go text/template used to render output instead of the
+	// output format
+	Template string
+	// TableOptions control the columns and order of table, CSV and TSV output
+	TableOptions tables.Options
+
+	// Timeout bounds the total time spent talking to the API, zero means
+	// there is no limit
+	Timeout time.Duration
+	// Retries is the number of times a request that failed for a transient
+	// reason is retried
+	Retries int
+	// DryRun stops any request that would change data from being sent
+	DryRun bool
+	// Context is applied to every Conch client created from the config
+	Context context.Context
+	// cancel releases the resources of Context once the command is done
+	cancel context.CancelFunc
+
+	dryRunLog   *conch.DryRunLog
+	tokenHelper *TokenHelper
+	// shell is the kosh shell session the command runs in, if any
+	shell *shellSession
 
 	logger.Logger
 }
@@ -44,11 +80,18 @@ const configTemplate = `
 * Version: {{ .Version }}
 * GitRev: {{ .GitRev }}
 
+* Profile: {{ .Profile }}
+* ConfigFile: {{ .ConfigFile }}
 * ConchENV: {{ .ConchENV }}
 * ConchURL: {{ .ConchURL }}
 * ConchToken: {{ .ConchToken }}
+* TokenCommand: {{ .TokenCommand }}
 
 * OutputJSON: {{ .OutputJSON }}
+* Output: {{ .Output }}
+* Timeout: {{ .Timeout }}
+* Retries: {{ .Retries }}
+* DryRun: {{ .DryRun }}
 
 Logger
 
@@ -60,26 +103,56 @@ Logger
 // String returns a string implementation of the config object
 func (c Config) String() string {
 	t, err := template.NewTemplate().Parse(configTemplate)
-	if err != nil {
-		log.Fatal(err)
-	}
+	fatalIf(err)
 
 	buf := &strings.Builder{}
-	if e := t.Execute(buf, c); e != nil {
-		log.Fatal(e)
-	}
+	fatalIf(t.Execute(buf, c))
 	return buf.String()
 }
 
 // ConchClient returns a configured client for the Conch API
 func (c Config) ConchClient() *conch.Client {
 	c.Debug("Creating Conch Client")
-	return conch.New(
-		conch.API(c.ConchURL),
-		conch.AuthToken(c.ConchToken),
-		conch.UserAgent(fmt.Sprintf("kosh %s", c.GitRev)),
-		conch.Logger(c.Logger),
-	)
+
+	token := c.ConchToken
+	if token == "" && c.TokenCommand != "" {
+		helper := c.tokenHelper
+		if helper == nil {
+			helper = NewTokenHelper(c.TokenCommand)
+		}
+		var e error
+		token, e = helper.Token()
+		fatalIf(e)
+	}
+
+	create := func() *conch.Client {
+		retry := conch.DefaultRetryPolicy
+		retry.MaxAttempts = c.Retries + 1
+
+		return conch.New(
+			conch.API(c.ConchURL),
+			conch.Retry(retry),
+			conch.AuthToken(token),
+			conch.UserAgent(fmt.Sprintf("kosh %s", c.GitRev)),
+			conch.Logger(c.Logger),
+		)
+	}
+
+	var client *conch.Client
+	if c.shell != nil {
+		key := fmt.Sprintf("%s %s %d %t %t", c.ConchURL, token, c.Retries, c.LevelDebug, c.LevelInfo)
+		client = c.shell.conchClient(key, create).New()
+	} else {
+		client = create()
+	}
+
+	if c.Context != nil {
+		client = client.WithContext(c.Context)
+	}
+	if c.dryRunLog != nil {
+		conch.DryRunWith(c.dryRunLog)(client)
+	}
+	return client
 }
 
 // Renderer is a function that takes some kind of data and an error and renders
@@ -101,27 +174,14 @@ func renderJSON(i interface{}) string {
 // configuraton and datatype
 func (c Config) RenderTo(w io.Writer) func(interface{}, error) {
 	return func(i interface{}, e error) {
-		if e != nil {
-			fmt.Fprintln(w, e)
-		}
-		if c.OutputJSON {
-			c.Debug("Outputting JSON")
-			fmt.Fprintln(w, renderJSON(i))
-			return
-		}
-		switch t := i.(type) {
-		case template.Templated:
-			s, e := template.Render(t)
-			fatalIf(e)
-
-			fmt.Fprintln(w, s)
-		case tables.Tabulable:
-			fmt.Fprintln(w, tables.Render(t))
-		case fmt.Stringer:
-			fmt.Fprintln(w, t)
-		default:
-			c.Debug("default renderer")
-			fmt.Fprintln(w, renderJSON(t))
+		// errors go to stderr, and there's no point rendering the zero value
+		// that comes with them
+		fatalIf(e)
+		if c.Filter != nil {
+			var err error
+			i, err = c.Filter.Apply(i)
+			fatalIf(err)
 		}
+		fatalIf(c.render(w, i))
 	}
 }
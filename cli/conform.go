@@ -0,0 +1,264 @@
+package cli
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/joyent/kosh/conch"
+	"github.com/joyent/kosh/conch/types"
+)
+
+// conformMismatch is a way a device differs from its hardware product
+type conformMismatch struct {
+	Device   string `json:"device"`
+	SKU      string `json:"sku"`
+	Check    string `json:"check"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// conformMismatches are the ways devices differ from their hardware products
+type conformMismatches []conformMismatch
+
+func (c conformMismatches) Len() int      { return len(c) }
+func (c conformMismatches) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+func (c conformMismatches) Less(i, j int) bool {
+	if c[i].Device != c[j].Device {
+		return c[i].Device < c[j].Device
+	}
+	return c[i].Check < c[j].Check
+}
+
+// Headers returns the list of headers for the table view
+func (c conformMismatches) Headers() []string {
+	return []string{
+		"Device",
+		"SKU",
+		"Check",
+		"Expected",
+		"Actual",
+	}
+}
+
+// ForEach iterates over each item in the list and applies a function to it
+func (c conformMismatches) ForEach(do func([]string)) {
+	for _, m := range c {
+		do([]string{m.Device, m.SKU, m.Check, m.Expected, m.Actual})
+	}
+}
+
+// devices counts the devices with a mismatch
+func (c conformMismatches) devices() int {
+	seen := map[string]bool{}
+	for _, m := range c {
+		seen[m.Device] = true
+	}
+	return len(seen)
+}
+
+// diskKind names the kind of a reported disk the way hardware products count
+// them, e.g. sas_hdd or nvme_ssd. USB and unrecognised disks are "".
+func diskKind(d types.Disk) string {
+	kind := strings.ToUpper(d.DriveType + " " + d.Transport)
+	switch {
+	case strings.Contains(kind, "USB"):
+		return ""
+	case strings.Contains(kind, "NVME"):
+		return "nvme_ssd"
+	}
+
+	var bus, media string
+	switch {
+	case strings.Contains(kind, "SATA"):
+		bus = "sata"
+	case strings.Contains(kind, "SAS"):
+		bus = "sas"
+	}
+	switch {
+	case strings.Contains(kind, "SSD"):
+		media = "ssd"
+	case strings.Contains(kind, "HDD"):
+		media = "hdd"
+	}
+	if bus == "" || media == "" {
+		return ""
+	}
+	return bus + "_" + media
+}
+
+// number reads a count or size that may be missing, a number or a string
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case string:
+		f, e := strconv.ParseFloat(n, 64)
+		return f, e == nil
+	}
+	return 0, false
+}
+
+// checkConformance compares the latest report of a device with the hardware
+// its product should have. Product sizes are in GB, reported disk sizes are
+// in MB, and a disk is the right size if it's within 10% of the product's.
+//
+// A count of 0 means something different for disks than for the rest. Every
+// server has CPUs, DIMMs and NICs, so a product with 0 of them hasn't said how
+// many. Most products have no disks of several kinds, so 0 disks of a kind
+// means none, and a disk of that kind is a mismatch.
+func checkConformance(device types.DetailedDevice, product types.HardwareProductCreate) conformMismatches {
+	var mismatches conformMismatches
+	mismatch := func(check string, expected, actual interface{}) {
+		mismatches = append(mismatches, conformMismatch{
+			Device:   string(device.SerialNumber),
+			SKU:      string(product.Sku),
+			Check:    check,
+			Expected: fmt.Sprint(expected),
+			Actual:   fmt.Sprint(actual),
+		})
+	}
+
+	report := device.LatestReport
+	if report.SerialNumber == "" && report.ProductName == "" {
+		mismatch("report", "a device report", "none")
+		return mismatches
+	}
+
+	// counts of 0 for CPUs, DIMMs and NICs are products that don't say, see
+	// above for why disks are different
+	count := func(check string, expected, actual int) {
+		if expected > 0 && expected != actual {
+			mismatch(check, expected, actual)
+		}
+	}
+	count("cpu_num", product.CPUNum, len(report.Cpus))
+	count("nics_num", product.NicsNum, len(report.Interfaces))
+
+	dimms := 0
+	ram := 0.0
+	for _, d := range report.Dimms {
+		if size, ok := number(d.MemorySize); ok && size > 0 {
+			dimms++
+			ram += size
+		}
+	}
+	count("dimms_num", product.DimmsNum, dimms)
+	if product.RAMTotal > 0 && float64(product.RAMTotal) != ram {
+		mismatch("ram_total", fmt.Sprintf("%d GB", product.RAMTotal), fmt.Sprintf("%s GB", jsonCell(ram)))
+	}
+
+	disks := map[string][]string{}
+	for serial, d := range report.Disks {
+		if kind := diskKind(d); kind != "" {
+			disks[kind] = append(disks[kind], serial)
+		}
+	}
+
+	for _, expected := range []struct {
+		kind  string
+		count int
+		size  interface{}
+	}{
+		{"sas_hdd", product.SasHddNum, product.SasHddSize},
+		{"sas_ssd", product.SasSsdNum, product.SasSsdSize},
+		{"sata_hdd", product.SataHddNum, product.SataHddSize},
+		{"sata_ssd", product.SataSsdNum, product.SataSsdSize},
+		{"nvme_ssd", product.NvmeSsdNum, product.NvmeSsdSize},
+	} {
+		// unlike count, 0 disks of a kind is checked
+		serials := disks[expected.kind]
+		sort.Strings(serials)
+		if len(serials) != expected.count {
+			mismatch(expected.kind+"_num", expected.count, len(serials))
+		}
+
+		size, ok := number(expected.size)
+		if !ok || size <= 0 {
+			continue
+		}
+		for _, serial := range serials {
+			actual := float64(report.Disks[serial].Size) / 1000
+			if math.Abs(actual-size) > size/10 {
+				mismatch(expected.kind+"_size", fmt.Sprintf("%s GB", jsonCell(size)), fmt.Sprintf("%s: %s GB", serial, jsonCell(math.Round(actual))))
+			}
+		}
+	}
+
+	sort.Sort(mismatches)
+	return mismatches
+}
+
+// conformDevices checks each device against its hardware product, with at
+// most parallel devices at a time. Products are only fetched once.
+func conformDevices(c *conch.Client, devices types.Devices, parallel int) conformMismatches {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	var (
+		lock       sync.Mutex
+		wg         sync.WaitGroup
+		mismatches conformMismatches
+		products   = map[types.UUID]*types.HardwareProductCreate{}
+		slots      = make(chan struct{}, parallel)
+	)
+
+	product := func(id types.UUID) (types.HardwareProductCreate, error) {
+		lock.Lock()
+		p, ok := products[id]
+		lock.Unlock()
+		if ok {
+			return *p, nil
+		}
+		fetched, e := c.GetHardwareProductProfile(id.String())
+		if e != nil {
+			return fetched, e
+		}
+		lock.Lock()
+		products[id] = &fetched
+		lock.Unlock()
+		return fetched, nil
+	}
+
+	for _, d := range devices {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(d types.Device) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			var found conformMismatches
+			detailed, e := c.GetDeviceByID(d.ID)
+			var p types.HardwareProductCreate
+			if e == nil {
+				p, e = product(d.HardwareProductID)
+			}
+			if e != nil {
+				found = conformMismatches{{
+					Device: string(d.SerialNumber),
+					SKU:    string(d.Sku),
+					Check:  "error",
+					Actual: strings.SplitN(e.Error(), "\n", 2)[0],
+				}}
+			} else {
+				found = checkConformance(detailed, p)
+			}
+
+			lock.Lock()
+			mismatches = append(mismatches, found...)
+			lock.Unlock()
+		}(d)
+	}
+
+	wg.Wait()
+	sort.Sort(mismatches)
+	return mismatches
+}
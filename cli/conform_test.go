@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/joyent/kosh/conch/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiskKind(t *testing.T) {
+	tests := []struct {
+		DriveType string
+		Transport string
+		Expected  string
+	}{
+		{"SAS_HDD", "", "sas_hdd"},
+		{"SAS_SSD", "", "sas_ssd"},
+		{"SATA_HDD", "", "sata_hdd"},
+		{"sata_ssd", "", "sata_ssd"},
+		{"HDD", "SAS", "sas_hdd"},
+		{"SSD", "sata", "sata_ssd"},
+		{"SSD", "NVMe", "nvme_ssd"},
+		{"NVME_SSD", "", "nvme_ssd"},
+		{"USB", "", ""},
+		{"SSD", "usb", ""},
+		{"HDD", "", ""},
+		{"", "SAS", ""},
+		{"", "", ""},
+	}
+
+	for _, test := range tests {
+		kind := diskKind(types.Disk{DriveType: test.DriveType, Transport: test.Transport})
+		assert.Equal(t, test.Expected, kind, "%q %q", test.DriveType, test.Transport)
+	}
+}
+
+// testConformDevice is a device with 2 CPUs, 2 32GB DIMMs, 2 NICs, 2 12TB
+// SAS HDDs, an NVMe SSD and a USB stick
+const testConformDevice = `{
+	"serial_number": "SN1",
+	"latest_report": {
+		"serial_number": "SN1",
+		"product_name": "Joyent-S10G5",
+		"cpus": [{}, {}],
+		"dimms": [
+			{"memory-locator": "A1", "memory-size": 32},
+			{"memory-locator": "B1", "memory-size": "32"},
+			{"memory-locator": "C1"}
+		],
+		"interfaces": {"eth0": {}, "eth1": {}},
+		"disks": {
+			"D1": {"drive_type": "SAS_HDD", "size": 12000000},
+			"D2": {"drive_type": "SAS_HDD", "size": 11500000},
+			"N1": {"drive_type": "SSD", "transport": "NVMe", "size": 1600000},
+			"U1": {"drive_type": "USB", "size": 16000}
+		}
+	}
+}`
+
+func TestCheckConformance(t *testing.T) {
+	// the product that matches the device
+	product := func() types.HardwareProductCreate {
+		return types.HardwareProductCreate{
+			Sku:         "600-0001",
+			CPUNum:      2,
+			DimmsNum:    2,
+			RAMTotal:    64,
+			NicsNum:     2,
+			SasHddNum:   2,
+			SasHddSize:  12000,
+			NvmeSsdNum:  1,
+			NvmeSsdSize: "1600",
+		}
+	}
+	mismatch := func(check, expected, actual string) conformMismatch {
+		return conformMismatch{Device: "SN1", SKU: "600-0001", Check: check, Expected: expected, Actual: actual}
+	}
+
+	tests := []struct {
+		Name     string
+		Edit     func(p *types.HardwareProductCreate)
+		Expected conformMismatches
+	}{
+		{
+			Name: "conforms",
+			Edit: func(p *types.HardwareProductCreate) {},
+		},
+		{
+			Name: "0 CPUs, DIMMs, NICs and RAM aren't checked",
+			Edit: func(p *types.HardwareProductCreate) {
+				p.CPUNum = 0
+				p.DimmsNum = 0
+				p.NicsNum = 0
+				p.RAMTotal = 0
+			},
+		},
+		{
+			Name: "0 disks of a kind means none",
+			Edit: func(p *types.HardwareProductCreate) {
+				p.NvmeSsdNum = 0
+				p.NvmeSsdSize = nil
+			},
+			Expected: conformMismatches{mismatch("nvme_ssd_num", "0", "1")},
+		},
+		{
+			Name: "counts",
+			Edit: func(p *types.HardwareProductCreate) {
+				p.CPUNum = 1
+				p.DimmsNum = 4
+				p.RAMTotal = 128
+				p.NicsNum = 4
+				p.SataSsdNum = 2
+			},
+			Expected: conformMismatches{
+				mismatch("cpu_num", "1", "2"),
+				mismatch("dimms_num", "4", "2"),
+				mismatch("nics_num", "4", "2"),
+				mismatch("ram_total", "128 GB", "64 GB"),
+				mismatch("sata_ssd_num", "2", "0"),
+			},
+		},
+		{
+			Name: "disk sizes are within 10%",
+			Edit: func(p *types.HardwareProductCreate) {
+				p.SasHddSize = 10800
+				p.NvmeSsdSize = "1800"
+			},
+			Expected: conformMismatches{
+				mismatch("nvme_ssd_size", "1800 GB", "N1: 1600 GB"),
+				mismatch("sas_hdd_size", "10800 GB", "D1: 12000 GB"),
+			},
+		},
+	}
+
+	var device types.DetailedDevice
+	assert.Nil(t, json.Unmarshal([]byte(testConformDevice), &device))
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			p := product()
+			test.Edit(&p)
+			assert.Equal(t, test.Expected, checkConformance(device, p))
+		})
+	}
+}
+
+func TestCheckConformanceNoReport(t *testing.T) {
+	assert.Equal(t, conformMismatches{{
+		Device:   "SN1",
+		SKU:      "600-0001",
+		Check:    "report",
+		Expected: "a device report",
+		Actual:   "none",
+	}}, checkConformance(types.DetailedDevice{SerialNumber: "SN1"}, types.HardwareProductCreate{Sku: "600-0001"}))
+}
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenExpiryMargin is how long before its expiry a token from a helper is
+// considered stale, so it doesn't expire in the middle of a command
+const tokenExpiryMargin = 30 * time.Second
+
+// TokenHelper gets API tokens from an external command, in the spirit of
+// git's credential helpers. The command is run by the shell and must print
+// either the bare token or a JSON document like
+//
+//	{"token": "...", "expiry": "2020-11-02T15:04:05Z"}
+//
+// on stdout. Its stdin and stderr are connected to the terminal so it can
+// prompt the user. The token is cached in memory until it expires, or for
+// the life of the process if no expiry was given.
+type TokenHelper struct {
+	Command string
+
+	mu      sync.Mutex
+	token   string
+	expiry  time.Time
+	fetched bool
+}
+
+type tokenHelperOutput struct {
+	Token  string    `json:"token"`
+	Expiry time.Time `json:"expiry"`
+}
+
+// NewTokenHelper returns a TokenHelper that runs the given command
+func NewTokenHelper(command string) *TokenHelper {
+	return &TokenHelper{Command: command}
+}
+
+// Token returns the cached token, running the helper command if there isn't
+// one or it has expired
+func (h *TokenHelper) Token() (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.fetched && (h.expiry.IsZero() || time.Now().Add(tokenExpiryMargin).Before(h.expiry)) {
+		return h.token, nil
+	}
+
+	out, err := h.run()
+	if err != nil {
+		return "", err
+	}
+	h.token, h.expiry, h.fetched = out.Token, out.Expiry, true
+	return h.token, nil
+}
+
+func (h *TokenHelper) run() (out tokenHelperOutput, err error) {
+	stdout := &bytes.Buffer{}
+	cmd := exec.Command("sh", "-c", h.Command)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+
+	if err = cmd.Run(); err != nil {
+		return out, fmt.Errorf("token command '%s' failed: %s", h.Command, err)
+	}
+
+	text := strings.TrimSpace(stdout.String())
+	if strings.HasPrefix(text, "{") {
+		if err = json.Unmarshal([]byte(text), &out); err != nil {
+			return out, fmt.Errorf("token command '%s' printed invalid JSON: %s", h.Command, err)
+		}
+	} else {
+		out.Token = text
+	}
+
+	if out.Token == "" {
+		return out, fmt.Errorf("token command '%s' didn't print a token", h.Command)
+	}
+	return out, nil
+}
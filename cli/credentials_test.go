@@ -0,0 +1,68 @@
+package cli_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joyent/kosh/cli"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenHelper(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kosh")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// each run of the helper appends a line to the log so the tests can
+	// count them
+	log := filepath.Join(dir, "runs")
+	runs := func() int {
+		b, _ := ioutil.ReadFile(log)
+		return strings.Count(string(b), "\n")
+	}
+
+	t.Run("plain token is cached", func(t *testing.T) {
+		os.Remove(log)
+		h := cli.NewTokenHelper("echo run >> " + log + "; echo '  abc123 '")
+
+		for i := 0; i < 3; i++ {
+			token, err := h.Token()
+			assert.Nil(t, err)
+			assert.Equal(t, "abc123", token)
+		}
+		assert.Equal(t, 1, runs())
+	})
+
+	t.Run("json token with expiry", func(t *testing.T) {
+		os.Remove(log)
+		expiry := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+		h := cli.NewTokenHelper("echo run >> " + log + `; echo '{"token":"abc123","expiry":"` + expiry + `"}'`)
+
+		token, err := h.Token()
+		assert.Nil(t, err)
+		assert.Equal(t, "abc123", token)
+		h.Token()
+		assert.Equal(t, 1, runs())
+	})
+
+	t.Run("expired token is fetched again", func(t *testing.T) {
+		os.Remove(log)
+		expiry := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+		h := cli.NewTokenHelper("echo run >> " + log + `; echo '{"token":"abc123","expiry":"` + expiry + `"}'`)
+
+		h.Token()
+		h.Token()
+		assert.Equal(t, 2, runs())
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		for _, command := range []string{"exit 1", "true", "echo '{\"token\":'", `echo '{"expiry":"2020-01-01T00:00:00Z"}'`} {
+			_, err := cli.NewTokenHelper(command).Token()
+			assert.Error(t, err, command)
+		}
+	})
+}
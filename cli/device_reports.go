@@ -1,20 +1,107 @@
 package cli
 
 import (
+	"encoding/json"
+	"fmt"
+	"os"
+
 	cli "github.com/jawher/mow.cli"
+	"github.com/joyent/kosh/collector"
 	"github.com/joyent/kosh/conch"
+	"github.com/joyent/kosh/conch/types"
 )
 
+// reportValidationOrder lists the problems in a group before what passed
+var reportValidationOrder = map[types.ValidationStatus]int{
+	"error": 0,
+	"fail":  1,
+	"pass":  2,
+}
+
+// reportValidation is the outcome of validating a device report, tabulated by
+// result and grouped by category and component
+type reportValidation types.ReportValidationResults
+
+func (v reportValidation) Len() int      { return len(v.Results) }
+func (v reportValidation) Swap(i, j int) { v.Results[i], v.Results[j] = v.Results[j], v.Results[i] }
+func (v reportValidation) Less(i, j int) bool {
+	a, b := v.Results[i], v.Results[j]
+	if a.Category != b.Category {
+		return a.Category < b.Category
+	}
+	if a.Component != b.Component {
+		return a.Component < b.Component
+	}
+	return reportValidationOrder[a.Status] < reportValidationOrder[b.Status]
+}
+
+// Headers returns the list of headers for the table view
+func (v reportValidation) Headers() []string {
+	return []string{
+		"Category",
+		"Component",
+		"Status",
+		"Message",
+		"Hint",
+	}
+}
+
+// ForEach iterates over each item in the list and applies a function to it
+func (v reportValidation) ForEach(do func([]string)) {
+	for _, r := range v.Results {
+		do([]string{r.Category, r.Component, string(r.Status), r.Message, jsonCell(r.Hint)})
+	}
+}
+
 func deviceReportCmd(cmd *cli.Cmd) {
 	cmd.Command("post", "Post a new device report", func(cmd *cli.Cmd) {
 		var conch *conch.Client
 
-		filePathArg := cmd.StringArg("FILE", "-", "Path to a JSON file that defines the layout. '-' indicates STDIN")
-
-		input, err := getInputReader(*filePathArg)
-		fatalIf(err)
+		filePathArg := cmd.StringArg("FILE", "-", "Path to a JSON file of the device report. '-' indicates STDIN")
+		cmd.Spec = "[FILE]"
 
 		cmd.Before = func() { conch = config.ConchClient() }
-		cmd.Action = func() { conch.SendDeviceReport(input) }
+		cmd.Action = func() {
+			// FILE is only parsed by the time the action runs
+			input, e := getInputReader(*filePathArg)
+			fatalIf(e)
+			fatalIf(conch.SendDeviceReport(input))
+		}
+	})
+
+	cmd.Command("validate", "Run the validations on a device report without storing the report or the results", func(cmd *cli.Cmd) {
+		filePathArg := cmd.StringArg("FILE", "-", "Path to a JSON file of the device report. '-' indicates STDIN")
+		cmd.Spec = "[FILE]"
+
+		cmd.Action = func() {
+			conch := config.ConchClient()
+			display := config.Renderer()
+
+			input, e := getInputReader(*filePathArg)
+			fatalIf(e)
+			results, e := conch.ValidateDeviceReport(input)
+			fatalIf(e)
+
+			display(reportValidation(results), nil)
+			if results.Status != "pass" {
+				fatalIf(fmt.Errorf("the report for %s did not pass validation, the status is %s", results.DeviceSerialNumber, results.Status))
+			}
+			fmt.Fprintf(os.Stderr, "the report for %s passed validation\n", results.DeviceSerialNumber)
+		}
+	})
+
+	cmd.Command("collect", "Build a device report for this Linux host from /sys and /proc", func(cmd *cli.Cmd) {
+		rootOpt := cmd.StringOpt("root", "/", "Directory to read sys and proc from, for a copy of another host's")
+		cmd.Spec = "[--root]"
+
+		cmd.Action = func() {
+			report, e := collector.New(*rootOpt).Collect()
+			fatalIf(e)
+
+			// always JSON, so it can be piped in to post
+			b, e := json.MarshalIndent(report, "", "  ")
+			fatalIf(e)
+			fmt.Println(string(b))
+		}
 	})
 }
@@ -2,17 +2,42 @@ package cli
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"strings"
 
 	cli "github.com/jawher/mow.cli"
 	"github.com/joyent/kosh/conch"
+	"github.com/joyent/kosh/conch/types"
 )
 
 func devicesCmd(cmd *cli.Cmd) {
 	cmd.Before = config.requireAuth
 	cmd.Command("search s", "Search for devices", deviceSearchCmd)
+	cmd.Command("diff", "Compare the latest reports of two devices of the same SKU", devicesDiffCmd)
+}
+
+func devicesDiffCmd(cmd *cli.Cmd) {
+	var (
+		aArg = cmd.StringArg("A", "", "UUID or serial number of the first device")
+		bArg = cmd.StringArg("B", "", "UUID or serial number of the second device")
+	)
+	cmd.Spec = "A B"
+
+	cmd.Action = func() {
+		conch := config.ConchClient()
+		display := config.Renderer()
+
+		a, e := conch.GetDeviceBySerial(*aArg)
+		fatalIf(e)
+		b, e := conch.GetDeviceBySerial(*bArg)
+		fatalIf(e)
+
+		if a.Sku != b.Sku {
+			fatalIf(errUsage("%s has SKU %s but %s has SKU %s, only devices of the same SKU can be compared", *aArg, a.Sku, *bArg, b.Sku))
+		}
+
+		display(diffDeviceReports(a.LatestReport, b.LatestReport, false), nil)
+	}
 }
 
 func deviceSearchCmd(cmd *cli.Cmd) {
@@ -79,6 +104,8 @@ func deviceCmd(cmd *cli.Cmd) {
 	cmd.Command("preflight", "Data that is only accurate inside preflight", devicePreflightCmd(id))
 	cmd.Command("phase", "Actions on the lifecycle phase of the device", devicePhaseCmd(id))
 	cmd.Command("report", "Get the most recently recorded report for this device", deviceDeviceReportCmd(id))
+	cmd.Command("inventory", "The CPUs, DIMMs, disks, NICs and temperatures of the device", deviceInventoryCmd(id))
+	cmd.Command("conform", "Compare the latest report with the hardware the product should have", deviceConformCmd(id))
 }
 
 func deviceGetCmd(id *string) func(cmd *cli.Cmd) {
@@ -92,6 +119,39 @@ func deviceGetCmd(id *string) func(cmd *cli.Cmd) {
 	}
 }
 
+func deviceInventoryCmd(id *string) func(cmd *cli.Cmd) {
+	return func(cmd *cli.Cmd) {
+		cmd.Action = func() {
+			conch := config.ConchClient()
+			display := config.Renderer()
+
+			d, e := conch.GetDeviceBySerial(*id)
+			display(getDeviceInventory(d), e)
+		}
+	}
+}
+
+func deviceConformCmd(id *string) func(cmd *cli.Cmd) {
+	return func(cmd *cli.Cmd) {
+		cmd.Action = func() {
+			conch := config.ConchClient()
+			display := config.Renderer()
+
+			d, e := conch.GetDeviceBySerial(*id)
+			fatalIf(e)
+			product, e := conch.GetHardwareProductProfile(d.HardwareProductID.String())
+			fatalIf(e)
+
+			mismatches := checkConformance(d, product)
+			display(mismatches, nil)
+			if len(mismatches) > 0 {
+				fatalIf(fmt.Errorf("%s does not conform to %s", d.SerialNumber, product.Sku))
+			}
+			fmt.Fprintf(os.Stderr, "%s conforms to %s\n", d.SerialNumber, product.Sku)
+		}
+	}
+}
+
 func deviceValidationsCmd(id *string) func(cmd *cli.Cmd) {
 	return func(cmd *cli.Cmd) {
 		cmd.Action = func() {
@@ -143,14 +203,14 @@ func deviceSettingCmd(id *string) func(cmd *cli.Cmd) {
 			cmd.Spec = "VALUE"
 
 			cmd.Action = func() {
-				conch.SetDeviceSetting(*id, key, value)
+				fatalIf(conch.SetDeviceSetting(*id, key, value))
 				display(conch.GetDeviceSettings(*id))
 			}
 		})
 
 		cmd.Command("delete rm", "Delete a particular device setting", func(cmd *cli.Cmd) {
 			cmd.Action = func() {
-				conch.DeleteDeviceSetting(*id, key)
+				fatalIf(conch.DeleteDeviceSetting(*id, key))
 				display(conch.GetDeviceSettings(*id))
 			}
 		})
@@ -192,14 +252,14 @@ func deviceTagCmd(id *string) func(cmd *cli.Cmd) {
 			cmd.Spec = "VALUE"
 
 			cmd.Action = func() {
-				conch.SetDeviceTag(*id, name, value)
+				fatalIf(conch.SetDeviceTag(*id, name, value))
 				display(conch.GetDeviceTags(*id))
 			}
 		})
 
 		cmd.Command("delete rm", "Delete a particular device tag", func(cmd *cli.Cmd) {
 			cmd.Action = func() {
-				conch.DeleteDeviceTag(*id, name)
+				fatalIf(conch.DeleteDeviceTag(*id, name))
 				display(conch.GetDeviceTags(*id))
 			}
 		})
@@ -290,9 +350,9 @@ func devicePhaseCmd(id *string) func(cmd *cli.Cmd) {
 			cmd.Spec = "PHASE"
 			cmd.Action = func() {
 				if !okPhase(phase) {
-					log.Fatal("Phase must be one of: " + prettyPhasesList())
+					fatalIf(errUsage("Phase must be one of: %s", prettyPhasesList()))
 				}
-				conch.SetDevicePhase(*id, phase)
+				fatalIf(conch.SetDevicePhase(*id, phase))
 				display(conch.GetDevicePhase(*id))
 			}
 		})
@@ -307,5 +367,45 @@ func deviceDeviceReportCmd(id *string) func(cmd *cli.Cmd) {
 			d, e := conch.GetDeviceBySerial(*id)
 			display(d.LatestReport, e)
 		}
+
+		cmd.Command("diff", "Compare the latest report with an earlier one", func(cmd *cli.Cmd) {
+			var (
+				againstOpt = cmd.StringOpt("against", "", "UUID of an earlier device report")
+				fileOpt    = cmd.StringOpt("file", "", "Path to a JSON file with an earlier device report. '-' indicates STDIN")
+			)
+			cmd.Spec = "[--against] [--file]"
+
+			cmd.Action = func() {
+				conch := config.ConchClient()
+				display := config.Renderer()
+
+				if (*againstOpt == "") == (*fileOpt == "") {
+					fatalIf(errUsage("give either --against or --file"))
+				}
+
+				d, e := conch.GetDeviceBySerial(*id)
+				fatalIf(e)
+
+				var old types.DeviceReport
+				if *againstOpt != "" {
+					row, e := conch.GetDeviceReportByID(*againstOpt)
+					fatalIf(e)
+					if row.DeviceID != d.ID {
+						fatalIf(errUsage("device report %s is for device %s, not %s", *againstOpt, row.DeviceID, d.ID))
+					}
+					old, e = toDeviceReport(row.Report)
+					fatalIf(e)
+				} else {
+					input, e := getInputReader(*fileOpt)
+					fatalIf(e)
+					old, e = readDeviceReport(input)
+					if e != nil {
+						fatalIf(fmt.Errorf("could not read %s: %w", *fileOpt, e))
+					}
+				}
+
+				display(diffDeviceReports(old, d.LatestReport, true), nil)
+			}
+		})
 	}
 }
@@ -0,0 +1,319 @@
+package cli
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/joyent/kosh/conch"
+	"github.com/joyent/kosh/conch/types"
+)
+
+// rackElevation is the front view of a rack, the slots of its layout with the
+// devices assigned to them
+type rackElevation struct {
+	Rack     string            `json:"rack"`
+	RackID   types.UUID        `json:"rack_id"`
+	Role     string            `json:"rack_role"`
+	RackSize int               `json:"rack_size"`
+	Phase    types.DevicePhase `json:"phase"`
+	Slots    []elevationSlot   `json:"slots"`
+
+	// ASCII draws the rack with plain ASCII instead of box drawing characters
+	ASCII bool `json:"-"`
+}
+
+// elevationSlot is a single slot of the rack layout, a slot without a device
+// serial number has nothing assigned to it
+type elevationSlot struct {
+	RackUnitStart int               `json:"rack_unit_start"`
+	RackUnitSize  int               `json:"rack_unit_size"`
+	SKU           string            `json:"sku"`
+	DeviceID      *types.UUID       `json:"device_id,omitempty"`
+	SerialNumber  string            `json:"device_serial_number,omitempty"`
+	AssetTag      string            `json:"device_asset_tag,omitempty"`
+	Phase         types.DevicePhase `json:"device_phase,omitempty"`
+}
+
+func (s elevationSlot) top() int { return s.RackUnitStart + s.RackUnitSize - 1 }
+
+func (s elevationSlot) label() string {
+	ru := fmt.Sprintf("%d", s.RackUnitStart)
+	if s.RackUnitSize > 1 {
+		ru = fmt.Sprintf("%d-%d", s.RackUnitStart, s.top())
+	}
+	if s.SerialNumber == "" {
+		return fmt.Sprintf("[%s] %s  (unassigned)", ru, s.SKU)
+	}
+	label := fmt.Sprintf("[%s] %s  %s", ru, s.SKU, s.SerialNumber)
+	if s.Phase != "" {
+		label += "  " + string(s.Phase)
+	}
+	return label
+}
+
+// getRackElevation puts together the layout, the assignments and the size of
+// the rack, with the phase of each assigned device.
+func getRackElevation(c *conch.Client, rack types.Rack) (rackElevation, error) {
+	elevation := rackElevation{
+		Rack:   string(rack.Name),
+		RackID: rack.ID,
+		Role:   string(rack.RackRoleName),
+		Phase:  rack.Phase,
+	}
+
+	role, e := c.GetRackRoleByID(rack.RackRoleID)
+	if e != nil {
+		return elevation, e
+	}
+	elevation.RackSize = int(role.RackSize)
+	if elevation.Role == "" {
+		elevation.Role = string(role.Name)
+	}
+
+	layout, e := c.GetRackLayout(rack.ID)
+	if e != nil {
+		return elevation, e
+	}
+	assignments, e := c.GetRackAssignments(rack.ID)
+	if e != nil {
+		return elevation, e
+	}
+
+	assigned := map[int]types.RackAssignment{}
+	for _, a := range assignments {
+		assigned[int(a.RackUnitStart)] = a
+	}
+
+	phases, e := getDevicePhases(c, rack.BuildID, assignments)
+	if e != nil {
+		return elevation, e
+	}
+
+	for _, l := range layout {
+		slot := elevationSlot{
+			RackUnitStart: int(l.RackUnitStart),
+			RackUnitSize:  int(l.RackUnitSize),
+			SKU:           string(l.Sku),
+		}
+		if a, ok := assigned[slot.RackUnitStart]; ok && a.DeviceSerialNumber != "" {
+			id := a.DeviceID
+			slot.DeviceID = &id
+			slot.SerialNumber = string(a.DeviceSerialNumber)
+			slot.AssetTag = string(a.DeviceAssetTag)
+			slot.Phase = phases[a.DeviceID]
+		}
+		elevation.Slots = append(elevation.Slots, slot)
+	}
+
+	sort.Slice(elevation.Slots, func(i, j int) bool {
+		return elevation.Slots[i].RackUnitStart > elevation.Slots[j].RackUnitStart
+	})
+	return elevation, nil
+}
+
+// getDevicePhases gets the phase of each device assigned to the rack. Most
+// devices are in the rack's build, so one listing of the build covers them,
+// the rest are looked up one at a time.
+func getDevicePhases(c *conch.Client, build types.UUID, assignments types.RackAssignments) (map[types.UUID]types.DevicePhase, error) {
+	phases := map[types.UUID]types.DevicePhase{}
+
+	var ids []types.UUID
+	for _, a := range assignments {
+		if a.DeviceID != (types.UUID{}) {
+			ids = append(ids, a.DeviceID)
+		}
+	}
+	if len(ids) == 0 {
+		return phases, nil
+	}
+
+	if build != (types.UUID{}) {
+		devices, e := c.GetAllBuildDevices(build.String())
+		if e != nil {
+			return phases, e
+		}
+		for _, d := range devices {
+			phases[d.ID] = d.Phase
+		}
+	}
+
+	for _, id := range ids {
+		if _, ok := phases[id]; ok {
+			continue
+		}
+		d, e := c.GetDeviceByID(id)
+		if e != nil {
+			return phases, e
+		}
+		phases[id] = d.Phase
+	}
+	return phases, nil
+}
+
+// height is the number of rack units drawn, the rack size unless the layout
+// goes past it
+func (r rackElevation) height() int {
+	height := r.RackSize
+	for _, s := range r.Slots {
+		if s.top() > height {
+			height = s.top()
+		}
+	}
+	return height
+}
+
+// units maps each rack unit to the slot occupying it, the index into Slots
+func (r rackElevation) units() map[int]int {
+	units := map[int]int{}
+	for i, s := range r.Slots {
+		for ru := s.RackUnitStart; ru <= s.top(); ru++ {
+			units[ru] = i
+		}
+	}
+	return units
+}
+
+type boxChars struct {
+	horizontal, vertical                  string
+	topLeft, topMiddle, topRight          string
+	middleLeft, middleMiddle, middleRight string
+	bottomLeft, bottomMiddle, bottomRight string
+}
+
+var (
+	unicodeBox = boxChars{"─", "│", "┌", "┬", "┐", "├", "┼", "┤", "└", "┴", "┘"}
+	asciiBox   = boxChars{"-", "|", "+", "+", "+", "+", "+", "+", "+", "+", "+"}
+)
+
+// String draws the rack from the top down, one line per rack unit
+func (r rackElevation) String() string {
+	box := unicodeBox
+	if r.ASCII {
+		box = asciiBox
+	}
+
+	height := r.height()
+	units := r.units()
+
+	width := len("empty")
+	for _, s := range r.Slots {
+		if l := len(s.label()); l > width {
+			width = l
+		}
+	}
+	ruWidth := len(fmt.Sprintf("%d", height))
+
+	line := func(left, middle, right string) string {
+		return left +
+			strings.Repeat(box.horizontal, ruWidth+2) +
+			middle +
+			strings.Repeat(box.horizontal, width+2) +
+			right + "\n"
+	}
+	row := func(ru int, content string) string {
+		return fmt.Sprintf("%s %*d %s %-*s %s\n", box.vertical, ruWidth, ru, box.vertical, width, content, box.vertical)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Rack %s (%s, %dU", r.Rack, r.Role, r.RackSize)
+	if r.Phase != "" {
+		fmt.Fprintf(&b, ", %s", r.Phase)
+	}
+	b.WriteString(")\n")
+
+	b.WriteString(line(box.topLeft, box.topMiddle, box.topRight))
+	above := 0
+	for ru := height; ru >= 1; ru-- {
+		// slots are numbered from 1, runs of empty units are 0
+		block := 0
+		if slot, used := units[ru]; used {
+			block = slot + 1
+		}
+		if ru < height && block != above {
+			b.WriteString(line(box.middleLeft, box.middleMiddle, box.middleRight))
+		}
+		above = block
+
+		content := "empty"
+		if block > 0 {
+			content = ""
+			if slot := r.Slots[block-1]; ru == slot.top() {
+				content = slot.label()
+			}
+		}
+		b.WriteString(row(ru, content))
+	}
+	b.WriteString(line(box.bottomLeft, box.bottomMiddle, box.bottomRight))
+
+	return b.String()
+}
+
+const (
+	svgUnitHeight = 20
+	svgRUWidth    = 40
+	svgSlotWidth  = 420
+	svgMargin     = 10
+)
+
+// SVG draws the rack as an SVG image, for printing
+func (r rackElevation) SVG(w io.Writer) error {
+	height := r.height()
+	units := r.units()
+
+	var b strings.Builder
+	top := svgMargin + svgUnitHeight
+	fmt.Fprintf(
+		&b,
+		`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="monospace" font-size="12">`+"\n",
+		2*svgMargin+svgRUWidth+svgSlotWidth,
+		top+height*svgUnitHeight+svgMargin,
+	)
+	fmt.Fprintf(
+		&b,
+		`<text x="%d" y="%d" font-weight="bold">%s</text>`+"\n",
+		svgMargin, svgMargin+12,
+		html.EscapeString(fmt.Sprintf("Rack %s (%s, %dU) %s", r.Rack, r.Role, r.RackSize, r.Phase)),
+	)
+
+	y := func(ru int) int { return top + (height-ru)*svgUnitHeight }
+	x := svgMargin + svgRUWidth
+
+	for ru := height; ru >= 1; ru-- {
+		fmt.Fprintf(
+			&b,
+			`<text x="%d" y="%d" text-anchor="end">%d</text>`+"\n",
+			x-6, y(ru)+14, ru,
+		)
+		if _, used := units[ru]; !used {
+			fmt.Fprintf(
+				&b,
+				`<rect x="%d" y="%d" width="%d" height="%d" fill="#f4f4f4" stroke="#cccccc" stroke-dasharray="4 2"/>`+"\n",
+				x, y(ru), svgSlotWidth, svgUnitHeight,
+			)
+		}
+	}
+
+	for _, s := range r.Slots {
+		fill := "#cfe3ff"
+		if s.SerialNumber == "" {
+			fill = "#ffe7b3"
+		}
+		fmt.Fprintf(
+			&b,
+			`<rect x="%d" y="%d" width="%d" height="%d" fill="%s" stroke="#333333"/>`+"\n",
+			x, y(s.top()), svgSlotWidth, s.RackUnitSize*svgUnitHeight, fill,
+		)
+		fmt.Fprintf(
+			&b,
+			`<text x="%d" y="%d">%s</text>`+"\n",
+			x+6, y(s.top())+14, html.EscapeString(s.label()),
+		)
+	}
+
+	b.WriteString("</svg>\n")
+	_, e := io.WriteString(w, b.String())
+	return e
+}
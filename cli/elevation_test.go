@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/joyent/kosh/conch"
+	"github.com/joyent/kosh/conch/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func testUUID(s string) types.UUID {
+	return types.UUID{UUID: uuid.Must(uuid.FromString(s))}
+}
+
+func testElevation() rackElevation {
+	id := testUUID("6dd0f1a4-9d8c-4f3e-a5f2-7d1a8a9f0c11")
+	return rackElevation{
+		Rack:     "r1",
+		Role:     "small",
+		RackSize: 6,
+		Phase:    "integration",
+		Slots: []elevationSlot{
+			{RackUnitStart: 4, RackUnitSize: 2, SKU: "600-0001", DeviceID: &id, SerialNumber: "SN1", Phase: "production"},
+			{RackUnitStart: 2, RackUnitSize: 1, SKU: "600-0002"},
+		},
+	}
+}
+
+func TestElevationString(t *testing.T) {
+	elevation := testElevation()
+	assert.Equal(t, `Rack r1 (small, 6U, integration)
+┌───┬─────────────────────────────────┐
+│ 6 │ empty                           │
+├───┼─────────────────────────────────┤
+│ 5 │ [4-5] 600-0001  SN1  production │
+│ 4 │                                 │
+├───┼─────────────────────────────────┤
+│ 3 │ empty                           │
+├───┼─────────────────────────────────┤
+│ 2 │ [2] 600-0002  (unassigned)      │
+├───┼─────────────────────────────────┤
+│ 1 │ empty                           │
+└───┴─────────────────────────────────┘
+`, elevation.String())
+
+	elevation.ASCII = true
+	elevation.Slots = elevation.Slots[1:]
+	elevation.RackSize = 2
+	assert.Equal(t, `Rack r1 (small, 2U, integration)
++---+----------------------------+
+| 2 | [2] 600-0002  (unassigned) |
++---+----------------------------+
+| 1 | empty                      |
++---+----------------------------+
+`, elevation.String())
+}
+
+func TestElevationSVG(t *testing.T) {
+	elevation := testElevation()
+	elevation.Rack = "r<1>"
+
+	buf := &bytes.Buffer{}
+	assert.Nil(t, elevation.SVG(buf))
+	svg := buf.String()
+
+	assert.True(t, strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" width="480" height="160"`), svg)
+	assert.True(t, strings.HasSuffix(svg, "</svg>\n"))
+	assert.Contains(t, svg, "Rack r&lt;1&gt; (small, 6U) integration")
+	assert.Contains(t, svg, `<rect x="50" y="50" width="420" height="40" fill="#cfe3ff" stroke="#333333"/>`)
+	assert.Contains(t, svg, `<rect x="50" y="110" width="420" height="20" fill="#ffe7b3" stroke="#333333"/>`)
+	// the three empty units
+	assert.Equal(t, 3, strings.Count(svg, `stroke-dasharray="4 2"`))
+}
+
+func TestGetRackElevation(t *testing.T) {
+	const (
+		rolePath       = "/rack_role/00000000-0000-0000-0000-000000000002"
+		layoutPath     = "/rack/6dd0f1a4-9d8c-4f3e-a5f2-7d1a8a9f0c11/layout"
+		assignmentPath = "/rack/6dd0f1a4-9d8c-4f3e-a5f2-7d1a8a9f0c11/assignment"
+		buildPath      = "/build/00000000-0000-0000-0000-000000000001/device"
+		otherBuildPath = "/build/00000000-0000-0000-0000-000000000003/device"
+		devicePath     = "/device/00000000-0000-0000-0000-00000000000a"
+	)
+
+	tests := []struct {
+		Name     string
+		BuildID  types.UUID
+		Requests []string
+	}{
+		{
+			// the phases come from one listing of the build, not a request
+			// per device
+			Name:     "in the rack's build",
+			BuildID:  testUUID("00000000-0000-0000-0000-000000000001"),
+			Requests: []string{rolePath, layoutPath, assignmentPath, buildPath},
+		},
+		{
+			Name:     "in another build",
+			BuildID:  testUUID("00000000-0000-0000-0000-000000000003"),
+			Requests: []string{rolePath, layoutPath, assignmentPath, otherBuildPath, devicePath},
+		},
+		{
+			Name:     "a rack without a build",
+			Requests: []string{rolePath, layoutPath, assignmentPath, devicePath},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			var requests []string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path := strings.TrimSuffix(r.URL.Path, "/")
+				requests = append(requests, path)
+				w.Header().Set("Content-Type", "application/json")
+				switch path {
+				case rolePath:
+					w.Write([]byte(`{"name": "small", "rack_size": 6}`))
+				case layoutPath:
+					w.Write([]byte(`[
+						{"rack_unit_start": 2, "rack_unit_size": 1, "sku": "600-0002"},
+						{"rack_unit_start": 4, "rack_unit_size": 2, "sku": "600-0001"}
+					]`))
+				case assignmentPath:
+					w.Write([]byte(`[{
+						"rack_unit_start": 4, "rack_unit_size": 2, "sku": "600-0001",
+						"device_id": "00000000-0000-0000-0000-00000000000a",
+						"device_serial_number": "SN1"
+					}]`))
+				case buildPath:
+					w.Write([]byte(`[{"id": "00000000-0000-0000-0000-00000000000a", "phase": "production"}]`))
+				case otherBuildPath:
+					w.Write([]byte(`[]`))
+				case devicePath:
+					w.Write([]byte(`{"id": "00000000-0000-0000-0000-00000000000a", "phase": "production"}`))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer ts.Close()
+
+			rack := types.Rack{
+				ID:         testUUID("6dd0f1a4-9d8c-4f3e-a5f2-7d1a8a9f0c11"),
+				Name:       "r1",
+				BuildID:    test.BuildID,
+				RackRoleID: testUUID("00000000-0000-0000-0000-000000000002"),
+				Phase:      "integration",
+			}
+			elevation, err := getRackElevation(conch.New(conch.API(ts.URL)), rack)
+			assert.Nil(t, err)
+
+			expected := testElevation()
+			expected.RackID = rack.ID
+			deviceID := testUUID("00000000-0000-0000-0000-00000000000a")
+			expected.Slots[0].DeviceID = &deviceID
+			assert.Equal(t, expected, elevation)
+			assert.Equal(t, test.Requests, requests)
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+
+	cli "github.com/jawher/mow.cli"
+	"github.com/joyent/kosh/conch"
+)
+
+// Exit codes, so scripts can tell why kosh failed. Usage errors get the same
+// code mow.cli uses when it can't parse the command line.
+const (
+	ExitOK       = 0
+	ExitError    = 1 // anything not covered below
+	ExitUsage    = 2 // bad command line arguments or options, or input the API said 400 or 422 to
+	ExitAuth     = 3 // no token, or the API said 401 or 403
+	ExitNotFound = 4 // the API said 404, or a named thing doesn't exist
+	ExitConflict = 5 // the API said 409
+	ExitServer   = 6 // the API said 5xx
+	ExitNetwork  = 7 // the API couldn't be reached, or the request timed out
+
+	// ExitInterrupt is what shells report for a command killed by SIGINT
+	ExitInterrupt = 130
+)
+
+// usageError is an error in how kosh was invoked
+type usageError struct{ msg string }
+
+func (e usageError) Error() string { return e.msg }
+
+// errUsage returns an error that exits with ExitUsage
+func errUsage(format string, args ...interface{}) error {
+	return usageError{fmt.Sprintf(format, args...)}
+}
+
+// notFoundError is returned when a lookup done on the client side, like
+// finding a rack by name, comes up empty
+type notFoundError struct{ msg string }
+
+func (e notFoundError) Error() string { return e.msg }
+
+// errNotFound returns an error that exits with ExitNotFound
+func errNotFound(format string, args ...interface{}) error {
+	return notFoundError{fmt.Sprintf(format, args...)}
+}
+
+// authError is returned when there are no credentials to talk to the API with
+type authError struct{ msg string }
+
+func (e authError) Error() string { return e.msg }
+
+// ExitCode returns the exit code kosh uses for the error
+func ExitCode(e error) int {
+	var (
+		usage    usageError
+		notFound notFoundError
+		auth     authError
+		opErr    *net.OpError
+		dnsErr   *net.DNSError
+		urlErr   *url.Error
+	)
+
+	switch {
+	case e == nil:
+		return ExitOK
+	case errors.As(e, &usage), conch.IsBadRequest(e), conch.IsUnprocessable(e):
+		return ExitUsage
+	case errors.As(e, &auth), conch.IsUnauthorized(e), conch.IsForbidden(e):
+		return ExitAuth
+	case errors.As(e, &notFound), conch.IsNotFound(e):
+		return ExitNotFound
+	case conch.IsConflict(e):
+		return ExitConflict
+	case conch.IsServerError(e):
+		return ExitServer
+	case errors.Is(e, context.Canceled):
+		return ExitInterrupt
+	// not net.Error, which the errno of a missing file also satisfies
+	case errors.Is(e, context.DeadlineExceeded), errors.As(e, &opErr), errors.As(e, &dnsErr), errors.As(e, &urlErr):
+		return ExitNetwork
+	}
+	return ExitError
+}
+
+// errorJSON is how errors are reported when the output format is JSON
+type errorJSON struct {
+	Error      string                 `json:"error"`
+	ExitCode   int                    `json:"exit_code"`
+	Status     int                    `json:"status,omitempty"`
+	Details    []conch.APIErrorDetail `json:"details,omitempty"`
+	Method     string                 `json:"method,omitempty"`
+	URL        string                 `json:"url,omitempty"`
+	RequestID  string                 `json:"request_id,omitempty"`
+	APIVersion string                 `json:"api_version,omitempty"`
+}
+
+// writeError reports the error on the writer, as a JSON object if the output
+// format is one of the JSON ones
+func (c Config) writeError(w io.Writer, e error) {
+	switch c.OutputFormat() {
+	case OutputJSONFormat, OutputJSONPretty, OutputJSONLines:
+	default:
+		fmt.Fprintln(w, e)
+		return
+	}
+
+	out := errorJSON{Error: e.Error(), ExitCode: ExitCode(e)}
+	var apiErr *conch.APIError
+	if errors.As(e, &apiErr) {
+		out.Error = apiErr.Message
+		if out.Error == "" {
+			out.Error = apiErr.Status
+		}
+		out.Status = apiErr.StatusCode
+		out.Details = apiErr.Details
+		out.Method = apiErr.Method
+		out.URL = apiErr.URL
+		out.RequestID = apiErr.RequestID
+		out.APIVersion = apiErr.APIVersion
+	}
+
+	b, err := json.Marshal(out)
+	if err != nil {
+		fmt.Fprintln(w, e)
+		return
+	}
+	fmt.Fprintln(w, string(b))
+}
+
+// fatalIf reports the error on stderr and exits with the matching exit code,
+// or in kosh shell, stops the command
+func fatalIf(e error) {
+	if e != nil {
+		config.writeError(os.Stderr, e)
+		if config.shell != nil {
+			panic(shellAbort(ExitCode(e)))
+		}
+		cli.Exit(ExitCode(e))
+	}
+}
@@ -0,0 +1,45 @@
+package cli_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/joyent/kosh/cli"
+	"github.com/joyent/kosh/conch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		Err      error
+		Expected int
+	}{
+		{nil, cli.ExitOK},
+		{errors.New("boom"), cli.ExitError},
+		{&conch.APIError{StatusCode: http.StatusUnauthorized}, cli.ExitAuth},
+		{&conch.APIError{StatusCode: http.StatusForbidden}, cli.ExitAuth},
+		{&conch.APIError{StatusCode: http.StatusNotFound}, cli.ExitNotFound},
+		{&conch.APIError{StatusCode: http.StatusConflict}, cli.ExitConflict},
+		{&conch.APIError{StatusCode: http.StatusBadGateway}, cli.ExitServer},
+		{&conch.APIError{StatusCode: http.StatusBadRequest}, cli.ExitUsage},
+		{&conch.APIError{StatusCode: http.StatusUnprocessableEntity}, cli.ExitUsage},
+		{&conch.APIError{StatusCode: http.StatusTeapot}, cli.ExitError},
+		{fmt.Errorf("wrapped: %w", context.DeadlineExceeded), cli.ExitNetwork},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, cli.ExitNetwork},
+		{&net.DNSError{Err: "no such host", Name: "conch.invalid"}, cli.ExitNetwork},
+		{&url.Error{Op: "Get", URL: "http://conch.invalid", Err: errors.New("EOF")}, cli.ExitNetwork},
+		{&os.PathError{Op: "open", Path: "missing.json", Err: syscall.ENOENT}, cli.ExitError},
+		{fmt.Errorf("wrapped: %w", context.Canceled), cli.ExitInterrupt},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.Expected, cli.ExitCode(test.Err), fmt.Sprint(test.Err))
+	}
+}
@@ -1,7 +1,6 @@
 package cli
 
 import (
-	"errors"
 	"fmt"
 
 	cli "github.com/jawher/mow.cli"
@@ -44,7 +43,7 @@ func cmdCreateProduct(cmd *cli.Cmd) {
 			BiosFirmware:     *biosFirmware,
 			CPUType:          *cpuType,
 		}
-		conch.CreateHardwareProduct(create)
+		fatalIf(conch.CreateHardwareProduct(create))
 		display(conch.GetHardwareProductByID(*name))
 	}
 }
@@ -68,7 +67,7 @@ func cmdImportProduct(cmd *cli.Cmd) {
 		fatalIf(err)
 
 		p := conch.ReadHardwareProduct(in)
-		conch.CreateHardwareProduct(p)
+		fatalIf(conch.CreateHardwareProduct(p))
 		display(conch.GetHardwareProducts())
 	}
 }
@@ -97,7 +96,7 @@ func hardwareCmd(cmd *cli.Cmd) {
 			fatalIf(e)
 
 			if (hp == types.HardwareProduct{}) {
-				fatalIf(errors.New("Hardware Product not found for " + *idArg))
+				fatalIf(errNotFound("Hardware Product not found for %s", *idArg))
 			}
 		}
 		cmd.Action = func() { fmt.Println(hp) }
@@ -106,7 +105,7 @@ func hardwareCmd(cmd *cli.Cmd) {
 		})
 		cmd.Command("delete rm", "Remove a hardware product", func(cmd *cli.Cmd) {
 			cmd.Action = func() {
-				conch.DeleteHardwareProduct(hp.ID)
+				fatalIf(conch.DeleteHardwareProduct(hp.ID))
 				display(conch.GetHardwareProducts())
 			}
 		})
@@ -122,7 +121,7 @@ func hardwareCmd(cmd *cli.Cmd) {
 		cmd.Command("create", "Create a hardware vendor", func(cmd *cli.Cmd) {
 			name := cmd.StringArg("NAME", "", "The name of the hardware vendor.")
 			cmd.Action = func() {
-				conch.FindOrCreateHardwareVendor(*name)
+				display(conch.FindOrCreateHardwareVendor(*name))
 			}
 		})
 	})
@@ -138,8 +137,7 @@ func hardwareCmd(cmd *cli.Cmd) {
 			fatalIf(e)
 
 			if (hv == types.HardwareVendor{}) {
-				fmt.Println("Hardware Vendor not found for " + *idArg)
-				cli.Exit(1)
+				fatalIf(errNotFound("Hardware Vendor not found for %s", *idArg))
 			}
 		}
 
@@ -149,7 +147,7 @@ func hardwareCmd(cmd *cli.Cmd) {
 		})
 		cmd.Command("delete rm", "Remove a hardware vendor", func(cmd *cli.Cmd) {
 			cmd.Action = func() {
-				conch.DeleteHardwareVendor(hv.ID)
+				fatalIf(conch.DeleteHardwareVendor(hv.ID))
 			}
 		})
 	})
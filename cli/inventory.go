@@ -0,0 +1,310 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/joyent/kosh/conch/types"
+	"github.com/joyent/kosh/tables"
+)
+
+// deviceInventory is the hardware in a device, from what Conch has stored
+// about it and its latest report
+type deviceInventory struct {
+	SerialNumber string           `json:"serial_number"`
+	CPUs         []types.CpusItem `json:"cpus"`
+	DIMMs        inventoryDIMMs   `json:"dimms"`
+	Disks        inventoryDisks   `json:"disks"`
+	NICs         inventoryNICs    `json:"nics"`
+	Temperatures inventorySensors `json:"temperatures"`
+}
+
+// inventoryDIMM is a memory module
+type inventoryDIMM struct {
+	Locator      string      `json:"locator"`
+	Size         interface{} `json:"size"`
+	SerialNumber interface{} `json:"serial_number"`
+}
+
+type inventoryDIMMs []inventoryDIMM
+
+func (d inventoryDIMMs) Len() int           { return len(d) }
+func (d inventoryDIMMs) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+func (d inventoryDIMMs) Less(i, j int) bool { return d[i].Locator < d[j].Locator }
+
+// Headers returns the list of headers for the table view
+func (d inventoryDIMMs) Headers() []string {
+	return []string{"Locator", "Size", "Serial Number"}
+}
+
+// ForEach iterates over each item in the list and applies a function to it
+func (d inventoryDIMMs) ForEach(do func([]string)) {
+	for _, dimm := range d {
+		do([]string{dimm.Locator, jsonCell(dimm.Size), jsonCell(dimm.SerialNumber)})
+	}
+}
+
+// inventoryDisk is a disk, where it is and what state it's in
+type inventoryDisk struct {
+	Enclosure    string      `json:"enclosure"`
+	Slot         string      `json:"slot"`
+	SerialNumber string      `json:"serial_number"`
+	Vendor       interface{} `json:"vendor"`
+	Model        interface{} `json:"model"`
+	Firmware     interface{} `json:"firmware"`
+	Health       interface{} `json:"health"`
+	Size         interface{} `json:"size"`
+	DriveType    interface{} `json:"drive_type"`
+}
+
+type inventoryDisks []inventoryDisk
+
+func (d inventoryDisks) Len() int      { return len(d) }
+func (d inventoryDisks) Swap(i, j int) { d[i], d[j] = d[j], d[i] }
+func (d inventoryDisks) Less(i, j int) bool {
+	if d[i].Enclosure != d[j].Enclosure {
+		return lessNumeric(d[i].Enclosure, d[j].Enclosure)
+	}
+	return lessNumeric(d[i].Slot, d[j].Slot)
+}
+
+// Headers returns the list of headers for the table view
+func (d inventoryDisks) Headers() []string {
+	return []string{
+		"Enclosure",
+		"Slot",
+		"Serial Number",
+		"Vendor",
+		"Model",
+		"Firmware",
+		"Health",
+		"Size",
+		"Type",
+	}
+}
+
+// ForEach iterates over each item in the list and applies a function to it
+func (d inventoryDisks) ForEach(do func([]string)) {
+	for _, disk := range d {
+		do([]string{
+			disk.Enclosure,
+			disk.Slot,
+			disk.SerialNumber,
+			jsonCell(disk.Vendor),
+			jsonCell(disk.Model),
+			jsonCell(disk.Firmware),
+			jsonCell(disk.Health),
+			jsonCell(disk.Size),
+			jsonCell(disk.DriveType),
+		})
+	}
+}
+
+// inventoryNIC is a network interface and what it's plugged in to
+type inventoryNIC struct {
+	Name       string      `json:"name"`
+	Mac        string      `json:"mac"`
+	PeerMac    interface{} `json:"peer_mac"`
+	PeerSwitch interface{} `json:"peer_switch"`
+	PeerPort   interface{} `json:"peer_port"`
+	State      interface{} `json:"state"`
+}
+
+type inventoryNICs []inventoryNIC
+
+func (n inventoryNICs) Len() int           { return len(n) }
+func (n inventoryNICs) Swap(i, j int)      { n[i], n[j] = n[j], n[i] }
+func (n inventoryNICs) Less(i, j int) bool { return n[i].Name < n[j].Name }
+
+// Headers returns the list of headers for the table view
+func (n inventoryNICs) Headers() []string {
+	return []string{"Name", "MAC", "Peer MAC", "Peer Switch", "Peer Port", "State"}
+}
+
+// ForEach iterates over each item in the list and applies a function to it
+func (n inventoryNICs) ForEach(do func([]string)) {
+	for _, nic := range n {
+		do([]string{
+			nic.Name,
+			nic.Mac,
+			jsonCell(nic.PeerMac),
+			jsonCell(nic.PeerSwitch),
+			jsonCell(nic.PeerPort),
+			jsonCell(nic.State),
+		})
+	}
+}
+
+// inventorySensor is a temperature reading
+type inventorySensor struct {
+	Sensor      string      `json:"sensor"`
+	Temperature interface{} `json:"temperature"`
+
+	// the position in the list, sorting keeps the order sensors were added in
+	n int
+}
+
+type inventorySensors []inventorySensor
+
+func (s inventorySensors) Len() int           { return len(s) }
+func (s inventorySensors) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s inventorySensors) Less(i, j int) bool { return s[i].n < s[j].n }
+
+// Headers returns the list of headers for the table view
+func (s inventorySensors) Headers() []string { return []string{"Sensor", "Temperature"} }
+
+// ForEach iterates over each item in the list and applies a function to it
+func (s inventorySensors) ForEach(do func([]string)) {
+	for _, sensor := range s {
+		do([]string{sensor.Sensor, jsonCell(sensor.Temperature)})
+	}
+}
+
+// lessNumeric orders strings holding numbers by their value
+func lessNumeric(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
+
+// getDeviceInventory puts together the inventory of a device. Disks and NICs
+// come from what Conch has stored, falling back to the latest report, which
+// is the only source of CPUs, DIMMs and temperatures.
+func getDeviceInventory(d types.DetailedDevice) deviceInventory {
+	report := d.LatestReport
+	inventory := deviceInventory{
+		SerialNumber: string(d.SerialNumber),
+		CPUs:         report.Cpus,
+		DIMMs:        inventoryDIMMs{},
+		Disks:        inventoryDisks{},
+		NICs:         inventoryNICs{},
+		Temperatures: inventorySensors{},
+	}
+	if inventory.CPUs == nil {
+		inventory.CPUs = []types.CpusItem{}
+	}
+
+	for _, dimm := range report.Dimms {
+		inventory.DIMMs = append(inventory.DIMMs, inventoryDIMM{
+			Locator:      dimm.MemoryLocator,
+			Size:         dimm.MemorySize,
+			SerialNumber: dimm.MemorySerialNumber,
+		})
+	}
+
+	for _, disk := range d.Disks {
+		inventory.Disks = append(inventory.Disks, inventoryDisk{
+			Enclosure:    fmt.Sprintf("%d", disk.Enclosure),
+			Slot:         fmt.Sprintf("%d", disk.Slot),
+			SerialNumber: string(disk.SerialNumber),
+			Vendor:       disk.Vendor,
+			Model:        disk.Model,
+			Firmware:     disk.Firmware,
+			Health:       disk.Health,
+			Size:         disk.Size,
+			DriveType:    disk.DriveType,
+		})
+	}
+	if len(d.Disks) == 0 {
+		for serial, disk := range report.Disks {
+			inventory.Disks = append(inventory.Disks, inventoryDisk{
+				Enclosure:    jsonCell(disk.Enclosure),
+				Slot:         jsonCell(disk.Slot),
+				SerialNumber: serial,
+				Vendor:       disk.Vendor,
+				Model:        disk.Model,
+				Firmware:     disk.Firmware,
+				Health:       disk.Health,
+				Size:         disk.Size,
+				DriveType:    disk.DriveType,
+			})
+		}
+	}
+
+	// the state of an interface is only in the report
+	for _, nic := range d.Nics {
+		name := string(nic.IfaceName)
+		inventory.NICs = append(inventory.NICs, inventoryNIC{
+			Name:       name,
+			Mac:        string(nic.Mac),
+			PeerMac:    nic.PeerMac,
+			PeerSwitch: nic.PeerSwitch,
+			PeerPort:   nic.PeerPort,
+			State:      report.Interfaces[name].State,
+		})
+	}
+	if len(d.Nics) == 0 {
+		for name, nic := range report.Interfaces {
+			inventory.NICs = append(inventory.NICs, inventoryNIC{
+				Name:    name,
+				Mac:     string(nic.Mac),
+				PeerMac: nic.PeerMac,
+				State:   nic.State,
+			})
+		}
+	}
+
+	if t := report.Temp; t != nil {
+		for _, sensor := range []inventorySensor{
+			{Sensor: "CPU 0", Temperature: t.CPU0},
+			{Sensor: "CPU 1", Temperature: t.CPU1},
+			{Sensor: "Inlet", Temperature: t.Inlet},
+			{Sensor: "Exhaust", Temperature: t.Exhaust},
+		} {
+			if sensor.Temperature != nil {
+				inventory.Temperatures = append(inventory.Temperatures, sensor)
+			}
+		}
+	}
+	var disks []inventorySensor
+	for serial, disk := range report.Disks {
+		if disk.Temp != nil {
+			disks = append(disks, inventorySensor{Sensor: "Disk " + serial, Temperature: disk.Temp})
+		}
+	}
+	sort.Slice(disks, func(i, j int) bool { return disks[i].Sensor < disks[j].Sensor })
+	inventory.Temperatures = append(inventory.Temperatures, disks...)
+	for i := range inventory.Temperatures {
+		inventory.Temperatures[i].n = i
+	}
+
+	sort.Sort(inventory.DIMMs)
+	sort.Sort(inventory.Disks)
+	sort.Sort(inventory.NICs)
+
+	return inventory
+}
+
+// String draws a table for each part of the inventory
+func (d deviceInventory) String() string {
+	var b strings.Builder
+
+	cpus, e := newJSONTable(d.CPUs)
+	if e != nil {
+		cpus = jsonTable{}
+	}
+
+	sections := []struct {
+		title string
+		table tables.Tabulable
+	}{
+		{"CPUs", cpus},
+		{"DIMMs", d.DIMMs},
+		{"Disks", d.Disks},
+		{"NICs", d.NICs},
+		{"Temperatures", d.Temperatures},
+	}
+
+	fmt.Fprintf(&b, "Inventory of %s\n", d.SerialNumber)
+	for _, section := range sections {
+		fmt.Fprintf(&b, "\n%s:\n", section.title)
+		if section.table.Len() == 0 {
+			b.WriteString("  none reported\n")
+			continue
+		}
+		b.WriteString(tables.Render(section.table))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"github.com/joyent/kosh/conch/types"
+	"github.com/joyent/kosh/tables"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLessNumeric(t *testing.T) {
+	slots := []string{"10", "2", "1", "0", "11", "3"}
+	sort.Slice(slots, func(i, j int) bool { return lessNumeric(slots[i], slots[j]) })
+	assert.Equal(t, []string{"0", "1", "2", "3", "10", "11"}, slots)
+
+	assert.False(t, lessNumeric("2", "2"))
+	assert.True(t, lessNumeric("9", "10"))
+	assert.False(t, lessNumeric("10", "9"))
+}
+
+// inventoryRows is the table of a part of the inventory
+func inventoryRows(t tables.Tabulable) [][]string {
+	var rows [][]string
+	t.ForEach(func(row []string) { rows = append(rows, row) })
+	return rows
+}
+
+// testInventoryReport has a disk and an interface that Conch may also have
+// stored for the device
+const testInventoryReport = `{
+	"cpus": [{"core_count": 8}],
+	"dimms": [
+		{"memory-locator": "P1-DIMMB1", "memory-size": 32, "memory-serial-number": "M2"},
+		{"memory-locator": "P1-DIMMA1", "memory-size": 32, "memory-serial-number": "M1"}
+	],
+	"disks": {
+		"D10": {"enclosure": "0", "slot": "10", "vendor": "HGST", "temp": 30},
+		"D2": {"enclosure": "0", "slot": "2", "vendor": "HGST", "temp": 31}
+	},
+	"interfaces": {
+		"eth1": {"mac": "00:00:00:00:00:02", "state": "down"},
+		"eth0": {"mac": "00:00:00:00:00:01", "state": "up"}
+	},
+	"temp": {"cpu0": 40, "cpu1": 41, "inlet": 20}
+}`
+
+func TestGetDeviceInventory(t *testing.T) {
+	var d types.DetailedDevice
+	assert.Nil(t, json.Unmarshal([]byte(`{"serial_number": "SN1", "latest_report": `+testInventoryReport+`}`), &d))
+
+	// without anything stored, everything comes from the report
+	inventory := getDeviceInventory(d)
+	assert.Equal(t, "SN1", inventory.SerialNumber)
+	assert.Len(t, inventory.CPUs, 1)
+	assert.Equal(t, [][]string{
+		{"P1-DIMMA1", "32", "M1"},
+		{"P1-DIMMB1", "32", "M2"},
+	}, inventoryRows(inventory.DIMMs))
+	assert.Equal(t, [][]string{
+		{"0", "2", "D2", "HGST", "", "", "", "0", ""},
+		{"0", "10", "D10", "HGST", "", "", "", "0", ""},
+	}, inventoryRows(inventory.Disks))
+	assert.Equal(t, [][]string{
+		{"eth0", "00:00:00:00:00:01", "", "", "", "up"},
+		{"eth1", "00:00:00:00:00:02", "", "", "", "down"},
+	}, inventoryRows(inventory.NICs))
+	assert.Equal(t, [][]string{
+		{"CPU 0", "40"},
+		{"CPU 1", "41"},
+		{"Inlet", "20"},
+		{"Disk D10", "30"},
+		{"Disk D2", "31"},
+	}, inventoryRows(inventory.Temperatures))
+
+	// the disks and NICs Conch has stored are used over the report, with the
+	// state of each interface still taken from the report
+	assert.Nil(t, json.Unmarshal([]byte(`{
+		"disks": [
+			{"enclosure": 1, "slot": 11, "serial_number": "S11", "vendor": "Intel", "size": 100},
+			{"enclosure": 1, "slot": 3, "serial_number": "S3", "vendor": "Intel", "size": 100}
+		],
+		"nics": [
+			{"iface_name": "eth0", "mac": "00:00:00:00:00:01", "peer_switch": "sw1", "peer_port": "1/1"}
+		]
+	}`), &d))
+	inventory = getDeviceInventory(d)
+	assert.Equal(t, [][]string{
+		{"1", "3", "S3", "Intel", "", "", "", "100", ""},
+		{"1", "11", "S11", "Intel", "", "", "", "100", ""},
+	}, inventoryRows(inventory.Disks))
+	assert.Equal(t, [][]string{
+		{"eth0", "00:00:00:00:00:01", "", "sw1", "1/1", "up"},
+	}, inventoryRows(inventory.NICs))
+}
+
+func TestGetDeviceInventoryEmpty(t *testing.T) {
+	// every part is an empty list rather than null, for the JSON output
+	out, err := json.Marshal(getDeviceInventory(types.DetailedDevice{SerialNumber: "SN1"}))
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{
+		"serial_number": "SN1",
+		"cpus": [],
+		"dimms": [],
+		"disks": [],
+		"nics": [],
+		"temperatures": []
+	}`, string(out))
+}
@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/joyent/kosh/conch"
+	"github.com/joyent/kosh/conch/types"
+)
+
+// The outcomes of copying a layout to a rack
+const (
+	layoutCopied  = "copied"
+	layoutSkipped = "skipped"
+	layoutFailed  = "failed"
+)
+
+// layoutCopyResult is the outcome of copying a layout to a single rack
+type layoutCopyResult struct {
+	Rack   string     `json:"rack"`
+	RackID types.UUID `json:"rack_id"`
+	Status string     `json:"status"`
+	Error  string     `json:"error,omitempty"`
+}
+
+// layoutCopyResults is the outcome of copying a layout to each rack
+type layoutCopyResults []layoutCopyResult
+
+func (r layoutCopyResults) Len() int           { return len(r) }
+func (r layoutCopyResults) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+func (r layoutCopyResults) Less(i, j int) bool { return r[i].Rack < r[j].Rack }
+
+// Headers returns the list of headers for the table view
+func (r layoutCopyResults) Headers() []string {
+	return []string{
+		"Rack",
+		"ID",
+		"Status",
+		"Error",
+	}
+}
+
+// ForEach iterates over each item in the list and applies a function to it
+func (r layoutCopyResults) ForEach(do func([]string)) {
+	for _, result := range r {
+		id := ""
+		if result.RackID != (types.UUID{}) {
+			id = result.RackID.String()
+		}
+		do([]string{
+			result.Rack,
+			id,
+			result.Status,
+			result.Error,
+		})
+	}
+}
+
+// failed counts the racks the layout couldn't be copied to
+func (r layoutCopyResults) failed() int {
+	n := 0
+	for _, result := range r {
+		if result.Status == layoutFailed {
+			n++
+		}
+	}
+	return n
+}
+
+// layoutCopyTarget finds a rack to copy the layout to
+type layoutCopyTarget struct {
+	name string
+	find func() (types.Rack, error)
+}
+
+// copyRackLayout applies the layout to each of the target racks, with at most
+// parallel requests at a time. Racks that already have a layout are skipped
+// unless overwrite is set.
+func copyRackLayout(
+	c *conch.Client,
+	source types.UUID,
+	layout []types.RackLayoutUpdate,
+	targets []layoutCopyTarget,
+	overwrite bool,
+	parallel int,
+) layoutCopyResults {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	results := make(layoutCopyResults, len(targets))
+	slots := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+
+	for i, target := range targets {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, target layoutCopyTarget) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			results[i] = copyLayoutTo(c, source, layout, target, overwrite)
+		}(i, target)
+	}
+
+	wg.Wait()
+	return results
+}
+
+func copyLayoutTo(c *conch.Client, source types.UUID, layout []types.RackLayoutUpdate, target layoutCopyTarget, overwrite bool) layoutCopyResult {
+	result := layoutCopyResult{Rack: target.name}
+	failed := func(e error) layoutCopyResult {
+		// client errors carry the request on a second line, which doesn't
+		// fit in a table cell
+		result.Status = layoutFailed
+		result.Error = strings.SplitN(e.Error(), "\n", 2)[0]
+		return result
+	}
+
+	rack, e := target.find()
+	if e != nil {
+		return failed(e)
+	}
+	result.Rack = string(rack.Name)
+	result.RackID = rack.ID
+
+	if rack.ID == source {
+		result.Status = layoutSkipped
+		result.Error = "this is the source rack"
+		return result
+	}
+
+	if !overwrite {
+		existing, e := c.GetRackLayout(rack.ID)
+		if e != nil {
+			return failed(e)
+		}
+		if len(existing) > 0 {
+			result.Status = layoutSkipped
+			result.Error = "rack already has a layout, use --overwrite to replace it"
+			return result
+		}
+	}
+
+	if e := c.UpdateRackLayout(rack.ID, layout); e != nil {
+		return failed(e)
+	}
+	result.Status = layoutCopied
+	return result
+}
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/joyent/kosh/conch"
+	"github.com/joyent/kosh/conch/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopyRackLayout(t *testing.T) {
+	var (
+		source   = testUUID("00000000-0000-0000-0000-0000000000a1")
+		empty    = testUUID("00000000-0000-0000-0000-0000000000a2")
+		full     = testUUID("00000000-0000-0000-0000-0000000000a3")
+		broken   = testUUID("00000000-0000-0000-0000-0000000000a4")
+		product  = testUUID("00000000-0000-0000-0000-000000000001")
+		layout   = []types.RackLayoutUpdate{{HardwareProductID: product, RackUnitStart: 1}}
+		lock     sync.Mutex
+		updated  = map[string][]types.RackLayoutUpdate{}
+		running  int
+		parallel int
+	)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		running++
+		if running > parallel {
+			parallel = running
+		}
+		lock.Unlock()
+		defer func() {
+			lock.Lock()
+			running--
+			lock.Unlock()
+		}()
+		// long enough for the requests to overlap
+		time.Sleep(10 * time.Millisecond)
+
+		path := strings.TrimSuffix(r.URL.Path, "/")
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && path == "/rack/"+full.String()+"/layout":
+			w.Write([]byte(`[{"rack_unit_start": 1}]`))
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`[]`))
+		case path == "/rack/"+broken.String()+"/layout":
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "boom"}`))
+		default:
+			var update []types.RackLayoutUpdate
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&update))
+			lock.Lock()
+			updated[path] = update
+			lock.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer ts.Close()
+
+	target := func(name string, id types.UUID) layoutCopyTarget {
+		return layoutCopyTarget{name: name, find: func() (types.Rack, error) {
+			return types.Rack{ID: id, Name: types.MojoRelaxedPlaceholder(name)}, nil
+		}}
+	}
+	targets := []layoutCopyTarget{
+		target("source", source),
+		target("empty", empty),
+		target("full", full),
+		target("broken", broken),
+		{name: "missing", find: func() (types.Rack, error) { return types.Rack{}, errors.New("could not find the rack\nmore detail") }},
+	}
+
+	results := copyRackLayout(conch.New(conch.API(ts.URL)), source, layout, targets, false, 2)
+	assert.Equal(t, layoutCopyResults{
+		{Rack: "source", RackID: source, Status: layoutSkipped, Error: "this is the source rack"},
+		{Rack: "empty", RackID: empty, Status: layoutCopied},
+		{Rack: "full", RackID: full, Status: layoutSkipped, Error: "rack already has a layout, use --overwrite to replace it"},
+		{Rack: "broken", RackID: broken, Status: layoutFailed, Error: results[3].Error},
+		{Rack: "missing", Status: layoutFailed, Error: "could not find the rack"},
+	}, results)
+	assert.NotContains(t, results[3].Error, "\n")
+	assert.Equal(t, 2, results.failed())
+	assert.Equal(t, map[string][]types.RackLayoutUpdate{"/rack/" + empty.String() + "/layout": layout}, updated)
+	assert.True(t, parallel <= 2, "%d requests at once", parallel)
+
+	// with overwrite the existing layouts aren't looked at
+	updated = map[string][]types.RackLayoutUpdate{}
+	results = copyRackLayout(conch.New(conch.API(ts.URL)), source, layout, targets[1:3], true, 0)
+	assert.Equal(t, 0, results.failed())
+	assert.Equal(t, map[string][]types.RackLayoutUpdate{
+		"/rack/" + empty.String() + "/layout": layout,
+		"/rack/" + full.String() + "/layout":  layout,
+	}, updated)
+}
@@ -0,0 +1,237 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/joyent/kosh/conch"
+	"github.com/joyent/kosh/conch/types"
+)
+
+// layoutEntry is a single slot of a rack layout file, in the format written
+// by 'rack UUID layout export'. The hardware product can be given by ID or by
+// SKU.
+type layoutEntry struct {
+	HardwareProductID string `json:"hardware_product_id"`
+	SKU               string `json:"sku"`
+	RackUnitStart     int    `json:"rack_unit_start"`
+	RackUnitSize      int    `json:"rack_unit_size"`
+
+	line    int
+	product *types.HardwareProduct
+}
+
+// size is the number of rack units the slot takes, the product's size when
+// it's known
+func (e layoutEntry) size() int {
+	if e.product != nil && e.product.RackUnitSize > 0 {
+		return int(e.product.RackUnitSize)
+	}
+	if e.RackUnitSize > 0 {
+		return e.RackUnitSize
+	}
+	return 1
+}
+
+func (e layoutEntry) top() int { return e.RackUnitStart + e.size() - 1 }
+
+func (e layoutEntry) describe() string {
+	name := e.SKU
+	if e.product != nil {
+		name = string(e.product.SKU)
+	}
+	if name == "" {
+		name = e.HardwareProductID
+	}
+	if e.size() == 1 {
+		return fmt.Sprintf("RU %d (%s, line %d)", e.RackUnitStart, name, e.line)
+	}
+	return fmt.Sprintf("RU %d-%d (%s, line %d)", e.RackUnitStart, e.top(), name, e.line)
+}
+
+// layoutProblem is something wrong with a rack layout file
+type layoutProblem struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// readLayoutEntries decodes a layout file, remembering the line each entry
+// starts on
+func readLayoutEntries(input []byte) ([]layoutEntry, error) {
+	dec := json.NewDecoder(bytes.NewReader(input))
+
+	lineAt := func(offset int64) int {
+		return bytes.Count(input[:offset], []byte("\n")) + 1
+	}
+
+	if t, e := dec.Token(); e != nil {
+		return nil, e
+	} else if t != json.Delim('[') {
+		return nil, fmt.Errorf("line %d: expected a list of rack layout slots", lineAt(dec.InputOffset()))
+	}
+
+	var entries []layoutEntry
+	for dec.More() {
+		// the offset is at the end of the previous token, skip the
+		// separator and whitespace to find where the entry starts
+		offset := dec.InputOffset()
+		for offset < int64(len(input)) && bytes.IndexByte([]byte(", \t\r\n"), input[offset]) >= 0 {
+			offset++
+		}
+
+		var entry layoutEntry
+		if e := dec.Decode(&entry); e != nil {
+			return nil, fmt.Errorf("line %d: %s", lineAt(offset), e)
+		}
+		entry.line = lineAt(offset)
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// lintRackLayout checks the entries of a rack layout file against the rack
+// role and the hardware products, filling in the product of each entry and
+// returning every problem found
+func lintRackLayout(c *conch.Client, entries []layoutEntry, role types.RackRole) ([]layoutProblem, error) {
+	products, e := c.GetHardwareProducts()
+	if e != nil {
+		return nil, e
+	}
+	byID := map[string]types.HardwareProduct{}
+	bySKU := map[string]types.HardwareProduct{}
+	for _, p := range products {
+		byID[p.ID.String()] = p
+		bySKU[string(p.SKU)] = p
+	}
+
+	// the list of products doesn't include their size, get each product used
+	// once
+	detailed := map[string]*types.HardwareProduct{}
+
+	var problems []layoutProblem
+	problem := func(line int, format string, args ...interface{}) {
+		problems = append(problems, layoutProblem{line, fmt.Sprintf(format, args...)})
+	}
+
+	for i := range entries {
+		entry := &entries[i]
+
+		var (
+			product types.HardwareProduct
+			ok      bool
+		)
+		switch {
+		case entry.HardwareProductID != "":
+			if product, ok = byID[entry.HardwareProductID]; !ok {
+				problem(entry.line, "unknown hardware product ID %s", entry.HardwareProductID)
+			} else if entry.SKU != "" && entry.SKU != string(product.SKU) {
+				problem(entry.line, "SKU %s doesn't match hardware product %s, which has SKU %s", entry.SKU, entry.HardwareProductID, product.SKU)
+			}
+		case entry.SKU != "":
+			if product, ok = bySKU[entry.SKU]; !ok {
+				problem(entry.line, "unknown hardware product SKU %s", entry.SKU)
+			}
+		default:
+			problem(entry.line, "no hardware_product_id or sku")
+		}
+
+		if ok {
+			id := product.ID.String()
+			if _, seen := detailed[id]; !seen {
+				p, e := c.GetHardwareProductByID(id)
+				if e != nil {
+					return nil, e
+				}
+				detailed[id] = &p
+			}
+			entry.product = detailed[id]
+
+			if size := int(entry.product.RackUnitSize); entry.RackUnitSize > 0 && size > 0 && entry.RackUnitSize != size {
+				problem(entry.line, "rack_unit_size is %d but %s takes %d", entry.RackUnitSize, product.SKU, size)
+			}
+		}
+
+		if entry.RackUnitStart < 1 {
+			problem(entry.line, "rack_unit_start must be 1 or more, not %d", entry.RackUnitStart)
+		} else if role.RackSize > 0 && entry.top() > int(role.RackSize) {
+			problem(entry.line, "%s goes past the top of the %dU rack role %s", entry.describe(), role.RackSize, role.Name)
+		}
+	}
+
+	for i, a := range entries {
+		for _, b := range entries[i+1:] {
+			switch {
+			case a.RackUnitStart == b.RackUnitStart:
+				problem(b.line, "duplicate slot at RU %d, already on line %d", b.RackUnitStart, a.line)
+			case a.RackUnitStart <= b.top() && b.RackUnitStart <= a.top():
+				problem(b.line, "%s overlaps %s", b.describe(), a.describe())
+			}
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Line < problems[j].Line })
+	return problems, nil
+}
+
+// layoutUpdate turns linted entries into the update for the rack, filling in
+// product IDs for entries given by SKU
+func layoutUpdate(entries []layoutEntry) []types.RackLayoutUpdate {
+	update := make([]types.RackLayoutUpdate, 0, len(entries))
+	for _, entry := range entries {
+		u := types.RackLayoutUpdate{RackUnitStart: types.PositiveInteger(entry.RackUnitStart)}
+		if entry.product != nil {
+			u.HardwareProductID = entry.product.ID
+		}
+		update = append(update, u)
+	}
+	return update
+}
+
+// layoutRole returns the rack role a layout is checked against, the named
+// one or else the rack's own
+func layoutRole(c *conch.Client, rack types.Rack, roleName string) (types.RackRole, error) {
+	if roleName == "" {
+		return c.GetRackRoleByID(rack.RackRoleID)
+	}
+	role, e := c.GetRackRoleByName(roleName)
+	if e == nil && (role == types.RackRole{}) {
+		e = errNotFound("could not find rack role %s", roleName)
+	}
+	return role, e
+}
+
+// lintLayoutFile lints a layout file against the named rack role, or else the
+// rack's, and reports each problem on stderr. Without a rack, i.e. a zero
+// rack, the role must be named.
+func lintLayoutFile(c *conch.Client, rack types.Rack, roleName, path string, input []byte) ([]layoutEntry, error) {
+	if rack.ID == (types.UUID{}) && roleName == "" {
+		return nil, errUsage("--role is required when no rack is given")
+	}
+	role, e := layoutRole(c, rack, roleName)
+	if e != nil {
+		return nil, e
+	}
+
+	if path == "-" {
+		path = "stdin"
+	}
+
+	entries, e := readLayoutEntries(input)
+	if e != nil {
+		return nil, fmt.Errorf("%s: %s", path, e)
+	}
+	problems, e := lintRackLayout(c, entries, role)
+	if e != nil {
+		return nil, e
+	}
+	for _, p := range problems {
+		fmt.Fprintf(os.Stderr, "%s:%d: %s\n", path, p.Line, p.Message)
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s: found %d problems with the rack layout", path, len(problems))
+	}
+	return entries, nil
+}
@@ -0,0 +1,255 @@
+package cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/joyent/kosh/conch"
+	"github.com/joyent/kosh/conch/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadLayoutEntries(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Input    string
+		Expected []layoutEntry
+		Err      string
+	}{
+		{
+			Name:     "empty",
+			Input:    `[]`,
+			Expected: nil,
+		},
+		{
+			Name: "lines",
+			Input: `[
+  {"sku": "600-0001", "rack_unit_start": 1},
+
+  {
+    "hardware_product_id": "00000000-0000-0000-0000-000000000001",
+    "rack_unit_start": 3,
+    "rack_unit_size": 2
+  }
+]`,
+			Expected: []layoutEntry{
+				{SKU: "600-0001", RackUnitStart: 1, line: 2},
+				{HardwareProductID: "00000000-0000-0000-0000-000000000001", RackUnitStart: 3, RackUnitSize: 2, line: 4},
+			},
+		},
+		{
+			Name:  "not a list",
+			Input: "\n{}",
+			Err:   "line 2: expected a list of rack layout slots",
+		},
+		{
+			Name:  "bad entry",
+			Input: "[\n{\"sku\": \"a\"},\n{\"rack_unit_start\": \"one\"}\n]",
+			Err:   "line 3: json: cannot unmarshal string",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			entries, err := readLayoutEntries([]byte(test.Input))
+			if test.Err != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), test.Err)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, test.Expected, entries)
+		})
+	}
+}
+
+// testProductServer serves two hardware products, a 1U and a 2U, and two rack
+// roles, a 10U and a 2U, counting the requests for each
+func testProductServer(requests map[string]int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimSuffix(r.URL.Path, "/")
+		requests[path]++
+		w.Header().Set("Content-Type", "application/json")
+		switch path {
+		case "/hardware_product":
+			w.Write([]byte(`[
+				{"id": "00000000-0000-0000-0000-000000000001", "sku": "1U"},
+				{"id": "00000000-0000-0000-0000-000000000002", "sku": "2U"}
+			]`))
+		case "/hardware_product/00000000-0000-0000-0000-000000000001":
+			w.Write([]byte(`{"id": "00000000-0000-0000-0000-000000000001", "sku": "1U", "rack_unit_size": 1}`))
+		case "/hardware_product/00000000-0000-0000-0000-000000000002":
+			w.Write([]byte(`{"id": "00000000-0000-0000-0000-000000000002", "sku": "2U", "rack_unit_size": 2}`))
+		case "/rack_role/small":
+			w.Write([]byte(`{"id": "00000000-0000-0000-0000-0000000000c1", "name": "small", "rack_size": 10}`))
+		case "/rack_role/00000000-0000-0000-0000-0000000000c2":
+			w.Write([]byte(`{"id": "00000000-0000-0000-0000-0000000000c2", "name": "tiny", "rack_size": 2}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestLintRackLayout(t *testing.T) {
+	role := types.RackRole{Name: "small", RackSize: 10}
+
+	tests := []struct {
+		Name     string
+		Entries  []layoutEntry
+		Expected []layoutProblem
+	}{
+		{
+			Name: "good",
+			Entries: []layoutEntry{
+				{SKU: "2U", RackUnitStart: 1, line: 1},
+				{HardwareProductID: "00000000-0000-0000-0000-000000000001", RackUnitStart: 3, line: 2},
+				{SKU: "2U", RackUnitStart: 9, RackUnitSize: 2, line: 3},
+			},
+		},
+		{
+			Name: "unknown products",
+			Entries: []layoutEntry{
+				{SKU: "3U", RackUnitStart: 1, line: 1},
+				{HardwareProductID: "00000000-0000-0000-0000-000000000009", RackUnitStart: 2, line: 2},
+				{RackUnitStart: 3, line: 3},
+				{HardwareProductID: "00000000-0000-0000-0000-000000000001", SKU: "2U", RackUnitStart: 4, line: 4},
+			},
+			Expected: []layoutProblem{
+				{1, "unknown hardware product SKU 3U"},
+				{2, "unknown hardware product ID 00000000-0000-0000-0000-000000000009"},
+				{3, "no hardware_product_id or sku"},
+				{4, "SKU 2U doesn't match hardware product 00000000-0000-0000-0000-000000000001, which has SKU 1U"},
+			},
+		},
+		{
+			Name: "sizes",
+			Entries: []layoutEntry{
+				{SKU: "2U", RackUnitStart: 1, RackUnitSize: 1, line: 1},
+				{SKU: "1U", RackUnitStart: 0, line: 2},
+				{SKU: "2U", RackUnitStart: 10, line: 3},
+			},
+			Expected: []layoutProblem{
+				{1, "rack_unit_size is 1 but 2U takes 2"},
+				{2, "rack_unit_start must be 1 or more, not 0"},
+				{3, "RU 10-11 (2U, line 3) goes past the top of the 10U rack role small"},
+			},
+		},
+		{
+			Name: "overlaps",
+			Entries: []layoutEntry{
+				{SKU: "2U", RackUnitStart: 4, line: 1},
+				{SKU: "1U", RackUnitStart: 5, line: 2},
+				{SKU: "1U", RackUnitStart: 4, line: 3},
+				{SKU: "1U", RackUnitStart: 6, line: 4},
+			},
+			Expected: []layoutProblem{
+				{2, "RU 5 (1U, line 2) overlaps RU 4-5 (2U, line 1)"},
+				{3, "duplicate slot at RU 4, already on line 1"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			requests := map[string]int{}
+			ts := testProductServer(requests)
+			defer ts.Close()
+
+			problems, err := lintRackLayout(conch.New(conch.API(ts.URL)), test.Entries, role)
+			assert.Nil(t, err)
+			assert.Equal(t, test.Expected, problems)
+
+			// the list of products and each product used are only fetched
+			// once
+			for path, n := range requests {
+				assert.Equal(t, 1, n, path)
+			}
+		})
+	}
+}
+
+func TestLayoutUpdate(t *testing.T) {
+	requests := map[string]int{}
+	ts := testProductServer(requests)
+	defer ts.Close()
+
+	entries := []layoutEntry{
+		{SKU: "2U", RackUnitStart: 1, line: 1},
+		{HardwareProductID: "00000000-0000-0000-0000-000000000001", RackUnitStart: 3, line: 2},
+	}
+	problems, err := lintRackLayout(conch.New(conch.API(ts.URL)), entries, types.RackRole{RackSize: 10})
+	assert.Nil(t, err)
+	assert.Empty(t, problems)
+
+	assert.Equal(t, []types.RackLayoutUpdate{
+		{HardwareProductID: testUUID("00000000-0000-0000-0000-000000000002"), RackUnitStart: 1},
+		{HardwareProductID: testUUID("00000000-0000-0000-0000-000000000001"), RackUnitStart: 3},
+	}, layoutUpdate(entries))
+}
+
+func TestLintLayoutFile(t *testing.T) {
+	// fits in the 10U role but not the 2U one
+	input := []byte(`[{"sku": "2U", "rack_unit_start": 3}]`)
+	rack := types.Rack{
+		ID:         testUUID("00000000-0000-0000-0000-0000000000a1"),
+		RackRoleID: testUUID("00000000-0000-0000-0000-0000000000c2"),
+	}
+
+	tests := []struct {
+		Name     string
+		Rack     types.Rack
+		Role     string
+		Request  string
+		Problems bool
+	}{
+		{
+			Name:    "a named role without a rack",
+			Role:    "small",
+			Request: "/rack_role/small",
+		},
+		{
+			Name:     "the rack's role",
+			Rack:     rack,
+			Request:  "/rack_role/00000000-0000-0000-0000-0000000000c2",
+			Problems: true,
+		},
+		{
+			Name:    "a named role over the rack's",
+			Rack:    rack,
+			Role:    "small",
+			Request: "/rack_role/small",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			requests := map[string]int{}
+			ts := testProductServer(requests)
+			defer ts.Close()
+
+			entries, err := lintLayoutFile(conch.New(conch.API(ts.URL)), test.Rack, test.Role, "layout.json", input)
+			if test.Problems {
+				assert.EqualError(t, err, "layout.json: found 1 problems with the rack layout")
+				assert.Nil(t, entries)
+			} else {
+				assert.Nil(t, err)
+				assert.Len(t, entries, 1)
+			}
+			assert.Equal(t, 1, requests[test.Request])
+		})
+	}
+}
+
+func TestLintLayoutFileNoRole(t *testing.T) {
+	requests := map[string]int{}
+	ts := testProductServer(requests)
+	defer ts.Close()
+
+	// without a rack or a role there's nothing to check against
+	_, err := lintLayoutFile(conch.New(conch.API(ts.URL)), types.Rack{}, "", "-", []byte(`[]`))
+	assert.Equal(t, ExitUsage, ExitCode(err))
+	assert.Empty(t, requests)
+}
@@ -0,0 +1,182 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	cli "github.com/jawher/mow.cli"
+	"github.com/joyent/kosh/conch"
+	"github.com/joyent/kosh/conch/types"
+	"golang.org/x/term"
+)
+
+// defaultProfileName is the profile 'kosh login' creates when no profile is
+// in use yet
+const defaultProfileName = "default"
+
+// defaultTokenName returns a token name that tells the user where and when
+// the token was created, e.g. "laptop-2020-11-02-153045"
+func defaultTokenName() string {
+	host, e := os.Hostname()
+	if e != nil || host == "" {
+		host = "kosh"
+	}
+	return fmt.Sprintf("%s-%s", host, time.Now().Format("2006-01-02-150405"))
+}
+
+// prompt writes the question to stderr and reads a single line from the
+// reader
+func prompt(in *bufio.Reader, question string) (string, error) {
+	fmt.Fprint(os.Stderr, question)
+	answer, e := in.ReadString('\n')
+	if e != nil && !(e == io.EOF && answer != "") {
+		return "", e
+	}
+	return strings.TrimSpace(answer), nil
+}
+
+// promptPassword reads a password without echoing it when stdin is a
+// terminal, otherwise it falls back to reading a line
+func promptPassword(in *bufio.Reader, question string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return prompt(in, question)
+	}
+
+	fmt.Fprint(os.Stderr, question)
+	password, e := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	return string(password), e
+}
+
+// activeProfile loads the config file and returns the profile currently in
+// use, creating one for the current URL if there isn't one yet
+func activeProfile() (*ConfigFile, Profile) {
+	file, e := LoadConfigFile(config.ConfigFile)
+	fatalIf(e)
+
+	name := config.Profile
+	if name == "" {
+		name = file.Current
+	}
+	if name == "" {
+		name = defaultProfileName
+	}
+
+	profile, ok := file.Profiles[name]
+	if !ok {
+		profile = Profile{Name: name, URL: config.ConchURL}
+		if file.Current == "" {
+			file.Current = name
+		}
+	}
+	return file, profile
+}
+
+func loginCmd(cmd *cli.Cmd) {
+	var (
+		emailOpt = cmd.StringOpt("email user u", "", "Email address to log in with, prompted for if not given")
+		nameOpt  = cmd.StringOpt("name n", "", "Name of the new API token (default: hostname and date)")
+	)
+
+	cmd.Action = func() {
+		if config.DryRun {
+			fatalIf(errUsage("login can't be used with --dry-run"))
+		}
+
+		file, profile := activeProfile()
+		in := bufio.NewReader(os.Stdin)
+
+		email := *emailOpt
+		if email == "" {
+			var e error
+			email, e = prompt(in, "Email: ")
+			fatalIf(e)
+		}
+		password, e := promptPassword(in, "Password: ")
+		fatalIf(e)
+
+		// log in with a client without credentials, so a stale token in the
+		// profile doesn't get in the way and a token command isn't run
+		anonymous := config
+		anonymous.ConchToken, anonymous.TokenCommand, anonymous.tokenHelper = "", "", nil
+		client := anonymous.ConchClient()
+
+		login, e := client.Login(email, password)
+		fatalIf(e)
+		session := client.Authorization("Bearer " + login.JwtToken)
+
+		name := *nameOpt
+		if name == "" {
+			name = defaultTokenName()
+		}
+		token, e := session.CreateCurrentUserToken(types.NewUserTokenRequest{Name: name})
+		fatalIf(e)
+
+		// the API token replaces the login session
+		if e := session.Logout(); e != nil {
+			config.Debug(e)
+		}
+
+		config.ConchToken = token.Token
+		user, e := config.ConchClient().GetCurrentUser()
+		fatalIf(e)
+
+		profile.Token = token.Token
+		profile.TokenName = token.Name
+		file.Profiles[profile.Name] = profile
+		fatalIf(file.Save())
+
+		fmt.Printf(
+			"Logged in as %s, token '%s' saved to profile '%s' in %s\n",
+			user.Email,
+			token.Name,
+			profile.Name,
+			file.Path,
+		)
+	}
+}
+
+func logoutCmd(cmd *cli.Cmd) {
+	nameOpt := cmd.StringOpt("name n", "", "Name of the API token to delete, if it wasn't created by 'kosh login'")
+
+	cmd.Action = func() {
+		file, profile := activeProfile()
+		if profile.Token == "" {
+			fatalIf(fmt.Errorf("profile '%s' has no token, nothing to log out of", profile.Name))
+		}
+
+		name := *nameOpt
+		if name == "" {
+			name = profile.TokenName
+		}
+		if name == "" {
+			fatalIf(errUsage("the token name isn't known for this profile, use --name"))
+		}
+
+		config.ConchToken = profile.Token
+		e := config.ConchClient().DeleteCurrentUserToken(name)
+		switch {
+		case conch.IsUnauthorized(e):
+			// the token is already gone on the server, just forget it
+			config.Info(fmt.Sprintf("token '%s' was already invalid", name))
+		case e != nil:
+			fatalIf(e)
+		}
+
+		if config.DryRun {
+			return
+		}
+
+		profile.Token = ""
+		profile.TokenName = ""
+		file.Profiles[profile.Name] = profile
+		fatalIf(file.Save())
+
+		fmt.Printf("Logged out, token '%s' removed from profile '%s'\n", name, profile.Name)
+	}
+}
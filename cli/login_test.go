@@ -0,0 +1,90 @@
+package cli_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/joyent/kosh/cli"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginLogout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kosh")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimSuffix(r.URL.Path, "/")
+		requests = append(requests, r.Method+" "+path+" "+r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		switch path {
+		case "/login":
+			var login map[string]string
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&login))
+			assert.Equal(t, map[string]string{"email": "me@example.com", "password": "hunter2"}, login)
+			w.Write([]byte(`{"jwt_token": "session"}`))
+		case "/user/me/token":
+			w.Write([]byte(`{"name": "laptop", "token": "api-token"}`))
+		case "/user/me":
+			w.Write([]byte(`{"email": "me@example.com"}`))
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer ts.Close()
+
+	// the password is read from stdin
+	stdin, w, err := os.Pipe()
+	assert.Nil(t, err)
+	w.WriteString("hunter2\n")
+	w.Close()
+	defer func(f *os.File) { os.Stdin = f }(os.Stdin)
+	os.Stdin = stdin
+
+	configFile := filepath.Join(dir, "config.yaml")
+	marker := filepath.Join(dir, "token-command-ran")
+	config := cli.NewConfig("test", "test")
+	config.ConfigFile = configFile
+
+	// the token command is for API calls, logging in mustn't run it
+	app := cli.NewApp(config)
+	err = app.Run([]string{
+		"kosh", "-u", ts.URL, "--token-command", "touch " + marker,
+		"login", "--email", "me@example.com", "--name", "laptop",
+	})
+	assert.Nil(t, err)
+	_, err = os.Stat(marker)
+	assert.True(t, os.IsNotExist(err), "the token command was run")
+
+	assert.Equal(t, []string{
+		"POST /login Bearer",
+		"POST /user/me/token Bearer session",
+		"POST /logout Bearer session",
+		"GET /user/me Bearer api-token",
+	}, requests)
+
+	file, err := cli.LoadConfigFile(configFile)
+	assert.Nil(t, err)
+	profile, err := file.Profile("")
+	assert.Nil(t, err)
+	assert.Equal(t, "api-token", profile.Token)
+	assert.Equal(t, "laptop", profile.TokenName)
+	assert.Equal(t, ts.URL, profile.URL)
+
+	requests = nil
+	app = cli.NewApp(config)
+	assert.Nil(t, app.Run([]string{"kosh", "logout"}))
+	assert.Equal(t, []string{"DELETE /user/me/token/laptop Bearer api-token"}, requests)
+
+	file, err = cli.LoadConfigFile(configFile)
+	assert.Nil(t, err)
+	profile, _ = file.Profile("")
+	assert.Equal(t, "", profile.Token)
+}
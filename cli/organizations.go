@@ -38,13 +38,13 @@ func organizationsCmd(cmd *cli.Cmd) {
 
 		cmd.Spec = "NAME [OPTIONS]"
 		cmd.Action = func() {
-			conch.CreateOrganization(types.OrganizationCreate{
+			fatalIf(conch.CreateOrganization(types.OrganizationCreate{
 				Name:        types.MojoStandardPlaceholder(*nameArg),
 				Description: types.NonEmptyString(*descOpt),
 				Admins: []types.Admin{
 					types.Admin{Email: types.EmailAddress(*adminEmailArg)},
 				},
-			})
+			}))
 		}
 	})
 }
@@ -74,7 +74,7 @@ func organizationCmd(cmd *cli.Cmd) {
 
 	cmd.Command("delete rm", "Remove a specific organization", func(cmd *cli.Cmd) {
 		cmd.Action = func() {
-			conch.DeleteOrganization(o.ID)
+			fatalIf(conch.DeleteOrganization(o.ID))
 		}
 	})
 
@@ -107,19 +107,19 @@ func organizationCmd(cmd *cli.Cmd) {
 			cmd.Spec = "EMAIL [OPTIONS]"
 			cmd.Action = func() {
 				if !okBuildRole(*roleOpt) {
-					fatalIf(fmt.Errorf(
+					fatalIf(errUsage(
 						"'role' value must be one of: %s",
 						prettyBuildRoleList(),
 					))
 				}
-				conch.AddOrganizationUser(
+				fatalIf(conch.AddOrganizationUser(
 					o.ID,
 					types.OrganizationAddUser{
 						Email: types.EmailAddress(*userEmailArg),
 						Role:  types.Role(*roleOpt),
 					},
 					*sendEmailOpt,
-				)
+				))
 				display(conch.GetOrganizationByID(o.ID))
 			}
 		})
@@ -138,11 +138,11 @@ func organizationCmd(cmd *cli.Cmd) {
 			)
 			cmd.Spec = "EMAIL [OPTIONS]"
 			cmd.Action = func() {
-				conch.DeleteOrganizationUser(
+				fatalIf(conch.DeleteOrganizationUser(
 					o.ID,
 					*userEmailArg,
 					*sendEmailOpt,
-				)
+				))
 				display(conch.GetOrganizationByID(o.ID))
 			}
 		})
@@ -0,0 +1,284 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/joyent/kosh/tables"
+	"github.com/joyent/kosh/template"
+	"gopkg.in/yaml.v2"
+)
+
+// The formats accepted by --output
+const (
+	OutputTable      = "table"
+	OutputMarkdown   = "markdown"
+	OutputJSONFormat = "json"
+	OutputJSONPretty = "json-pretty"
+	OutputJSONLines  = "jsonl"
+	OutputYAML       = "yaml"
+	OutputCSV        = "csv"
+	OutputTSV        = "tsv"
+)
+
+var outputList = []string{
+	OutputTable,
+	OutputJSONFormat,
+	OutputJSONPretty,
+	OutputJSONLines,
+	OutputYAML,
+	OutputCSV,
+	OutputTSV,
+	OutputMarkdown,
+}
+
+func okOutput(output string) bool {
+	if output == "" {
+		return true
+	}
+	for _, o := range outputList {
+		if o == output {
+			return true
+		}
+	}
+	return false
+}
+
+// OutputFormat returns the output format in use, --json is shorthand for
+// --output json
+func (c Config) OutputFormat() string {
+	if c.Output != "" {
+		return c.Output
+	}
+	if c.OutputJSON {
+		return OutputJSONFormat
+	}
+	return OutputTable
+}
+
+// render writes the data to the writer in the configured output format
+func (c Config) render(w io.Writer, i interface{}) error {
+	if c.Template != "" {
+		s, e := template.Execute(c.Template, i)
+		if e != nil {
+			return e
+		}
+		_, e = io.WriteString(w, s)
+		return e
+	}
+
+	switch c.OutputFormat() {
+	case OutputJSONFormat:
+		c.Debug("Outputting JSON")
+		b, e := json.Marshal(i)
+		if e != nil {
+			return e
+		}
+		_, e = fmt.Fprintln(w, string(b))
+		return e
+
+	case OutputJSONPretty:
+		b, e := json.MarshalIndent(i, "", "  ")
+		if e != nil {
+			return e
+		}
+		_, e = fmt.Fprintln(w, string(b))
+		return e
+
+	case OutputJSONLines:
+		return renderJSONLines(w, i)
+
+	case OutputYAML:
+		return renderYAML(w, i)
+
+	case OutputCSV:
+		return renderDelimited(w, i, ',', c.TableOptions)
+
+	case OutputTSV:
+		return renderDelimited(w, i, '\t', c.TableOptions)
+
+	case OutputMarkdown:
+		return c.renderText(w, i, tables.RenderMarkdown)
+
+	default:
+		return c.renderText(w, i, tables.RenderWith)
+	}
+}
+
+// renderText is the human readable output: markdown compatible templates,
+// and tables drawn by the given function
+func (c Config) renderText(w io.Writer, i interface{}, table func(tables.Tabulable, tables.Options) (string, error)) error {
+	switch t := i.(type) {
+	case template.Templated:
+		s, e := template.Render(t)
+		if e != nil {
+			return e
+		}
+		fmt.Fprintln(w, s)
+	case tables.Tabulable:
+		s, e := table(t, c.TableOptions)
+		if e != nil {
+			return e
+		}
+		fmt.Fprintln(w, s)
+	case fmt.Stringer:
+		fmt.Fprintln(w, t)
+	default:
+		c.Debug("default renderer")
+		fmt.Fprintln(w, renderJSON(t))
+	}
+	return nil
+}
+
+// renderJSONLines writes each element of a list as a JSON document on its own
+// line. Anything that isn't a list is written as a single line.
+func renderJSONLines(w io.Writer, i interface{}) error {
+	enc := json.NewEncoder(w)
+
+	v := reflect.ValueOf(i)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return enc.Encode(i)
+	}
+	for n := 0; n < v.Len(); n++ {
+		if e := enc.Encode(v.Index(n).Interface()); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// renderYAML writes the data as YAML using the same field names as the JSON
+// output, by way of its JSON encoding
+func renderYAML(w io.Writer, i interface{}) error {
+	b, e := json.Marshal(i)
+	if e != nil {
+		return e
+	}
+	var data interface{}
+	if e := yaml.Unmarshal(b, &data); e != nil {
+		return e
+	}
+	out, e := yaml.Marshal(data)
+	if e != nil {
+		return e
+	}
+	_, e = w.Write(out)
+	return e
+}
+
+// renderDelimited writes Tabulable types using their table columns, anything
+// else is turned into rows and columns from its JSON encoding. UUIDs are
+// always written in full, the output is for other programs.
+func renderDelimited(w io.Writer, i interface{}, comma rune, opts tables.Options) error {
+	opts.FullUUIDs = true
+	if t, ok := i.(tables.Tabulable); ok {
+		return tables.RenderDelimited(w, t, comma, opts)
+	}
+	t, e := newJSONTable(i)
+	if e != nil {
+		return e
+	}
+	return tables.RenderDelimited(w, t, comma, opts)
+}
+
+// jsonTable is a Tabulable built from the JSON encoding of a value. A list of
+// objects becomes a row per object with a column per key, a single object
+// becomes a single row.
+type jsonTable struct {
+	headers []string
+	rows    []jsonRow
+}
+
+// jsonRow remembers where the row was in the data, so sorting keeps that order
+type jsonRow struct {
+	n     int
+	cells []string
+}
+
+func newJSONTable(i interface{}) (jsonTable, error) {
+	t := jsonTable{}
+
+	b, e := json.Marshal(i)
+	if e != nil {
+		return t, e
+	}
+	var data interface{}
+	if e := json.Unmarshal(b, &data); e != nil {
+		return t, e
+	}
+
+	var items []interface{}
+	switch d := data.(type) {
+	case []interface{}:
+		items = d
+	case nil:
+	default:
+		items = []interface{}{d}
+	}
+
+	columns := map[string]bool{}
+	for _, item := range items {
+		if obj, ok := item.(map[string]interface{}); ok {
+			for key := range obj {
+				columns[key] = true
+			}
+		}
+	}
+	for key := range columns {
+		t.headers = append(t.headers, key)
+	}
+	sort.Strings(t.headers)
+
+	// lists of plain values get a single column
+	if len(t.headers) == 0 {
+		t.headers = []string{"value"}
+		for _, item := range items {
+			t.rows = append(t.rows, jsonRow{len(t.rows), []string{jsonCell(item)}})
+		}
+		return t, nil
+	}
+
+	for _, item := range items {
+		obj, _ := item.(map[string]interface{})
+		row := make([]string, len(t.headers))
+		for n, key := range t.headers {
+			row[n] = jsonCell(obj[key])
+		}
+		t.rows = append(t.rows, jsonRow{len(t.rows), row})
+	}
+	return t, nil
+}
+
+// jsonCell renders a decoded JSON value as a single table cell
+func jsonCell(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	default:
+		b, _ := json.Marshal(t)
+		return strings.TrimSpace(string(b))
+	}
+}
+
+func (t jsonTable) Headers() []string { return t.headers }
+
+func (t jsonTable) ForEach(do func([]string)) {
+	for _, row := range t.rows {
+		do(row.cells)
+	}
+}
+
+func (t jsonTable) Len() int           { return len(t.rows) }
+func (t jsonTable) Swap(i, j int)      { t.rows[i], t.rows[j] = t.rows[j], t.rows[i] }
+func (t jsonTable) Less(i, j int) bool { return t.rows[i].n < t.rows[j].n }
@@ -0,0 +1,187 @@
+package cli_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/joyent/kosh/cli"
+	"github.com/joyent/kosh/conch/types"
+	"github.com/joyent/kosh/tables"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutputFormats(t *testing.T) {
+	vendors := types.HardwareVendors{
+		{Name: "Wistron"},
+		{Name: "Dell, Inc."},
+	}
+
+	tests := []struct {
+		Output   string
+		Data     interface{}
+		Expected string
+	}{
+		{
+			Output:   "csv",
+			Data:     vendors,
+			Expected: "Name,ID,Created,Updated\n\"Dell, Inc.\",00000000-0000-0000-0000-000000000000,,\nWistron,00000000-0000-0000-0000-000000000000,,\n",
+		},
+		{
+			Output:   "tsv",
+			Data:     vendors,
+			Expected: "Name\tID\tCreated\tUpdated\nDell, Inc.\t00000000-0000-0000-0000-000000000000\t\t\nWistron\t00000000-0000-0000-0000-000000000000\t\t\n",
+		},
+		{
+			Output:   "table",
+			Data:     types.HardwareVendors{{Name: "a|b"}},
+			Expected: "| NAME |    ID    | CREATED | UPDATED |\n|------|----------|---------|---------|\n| a|b  | 00000000 |         |         |\n\n",
+		},
+		{
+			Output:   "markdown",
+			Data:     types.HardwareVendors{{Name: "a|b"}},
+			Expected: "| Name | ID       | Created | Updated |\n|------|----------|---------|---------|\n| a\\|b | 00000000 |         |         |\n\n",
+		},
+		{
+			Output:   "csv",
+			Data:     types.Ping{Status: "ok"},
+			Expected: "status\nok\n",
+		},
+		{
+			Output:   "jsonl",
+			Data:     types.UserSettings{"a": "1"},
+			Expected: "{\"a\":\"1\"}\n",
+		},
+		{
+			Output:   "jsonl",
+			Data:     []types.Ping{{Status: "ok"}, {Status: "not ok"}},
+			Expected: "{\"status\":\"ok\"}\n{\"status\":\"not ok\"}\n",
+		},
+		{
+			Output:   "json-pretty",
+			Data:     types.Ping{Status: "ok"},
+			Expected: "{\n  \"status\": \"ok\"\n}\n",
+		},
+		{
+			Output:   "yaml",
+			Data:     []types.Ping{{Status: "ok"}},
+			Expected: "- status: ok\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Output, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			config := cli.NewConfig("test", "test")
+			config.Output = test.Output
+			config.RenderTo(buf)(test.Data, nil)
+			assert.Equal(t, test.Expected, buf.String())
+		})
+	}
+}
+
+func TestTableOptions(t *testing.T) {
+	devices := types.Devices{
+		{SerialNumber: "b", Hostname: "sw-2", Phase: "integration", Health: "fail", RackUnitStart: "10"},
+		{SerialNumber: "a", Hostname: "sw-1", Phase: "production", Health: "pass", RackUnitStart: "9"},
+		{SerialNumber: "c", Hostname: "sw-3", Phase: "integration", Health: "pass", RackUnitStart: "11"},
+	}
+
+	tests := []struct {
+		Name     string
+		Options  tables.Options
+		Expected string
+	}{
+		{
+			Name:     "columns",
+			Options:  tables.Options{Columns: []string{"serial", "health"}},
+			Expected: "Serial,Health\na,pass\nb,fail\nc,pass\n",
+		},
+		{
+			Name:     "sort by number",
+			Options:  tables.Options{Columns: []string{"serial"}, SortBy: "rack_unit"},
+			Expected: "Serial\na\nb\nc\n",
+		},
+		{
+			Name:     "sort by and reverse without headers",
+			Options:  tables.Options{Columns: []string{"hostname", "phase"}, SortBy: "Phase", Reverse: true, NoHeaders: true},
+			Expected: "sw-1,production\nsw-3,integration\nsw-2,integration\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			config := cli.NewConfig("test", "test")
+			config.Output = "csv"
+			config.TableOptions = test.Options
+			config.RenderTo(buf)(devices, nil)
+			assert.Equal(t, test.Expected, buf.String())
+		})
+	}
+
+	t.Run("wide", func(t *testing.T) {
+		headers, _, err := tables.Rows(devices, tables.Options{})
+		assert.Nil(t, err)
+		assert.NotContains(t, headers, "Health")
+
+		headers, _, err = tables.Rows(devices, tables.Options{Wide: true})
+		assert.Nil(t, err)
+		assert.Contains(t, headers, "Health")
+	})
+
+	t.Run("full UUIDs", func(t *testing.T) {
+		id := types.UUID{UUID: uuid.Must(uuid.FromString("6dd0f1a4-9d8c-4f3e-a5f2-7d1a8a9f0c11"))}
+		devices := types.Devices{{SerialNumber: "a", ID: id}}
+
+		_, rows, err := tables.Rows(devices, tables.Options{Columns: []string{"id"}})
+		assert.Nil(t, err)
+		assert.Equal(t, [][]string{{"6dd0f1a4"}}, rows)
+
+		_, rows, err = tables.Rows(devices, tables.Options{Columns: []string{"id"}, FullUUIDs: true})
+		assert.Nil(t, err)
+		assert.Equal(t, [][]string{{id.String()}}, rows)
+	})
+
+	t.Run("unknown column", func(t *testing.T) {
+		_, _, err := tables.Rows(devices, tables.Options{Columns: []string{"nope"}})
+		assert.Error(t, err)
+	})
+}
+
+func TestTemplateOutput(t *testing.T) {
+	devices := types.Devices{
+		{SerialNumber: "abc123", Phase: "integration", Hostname: "sw-1.example.com"},
+		{SerialNumber: "def456", Phase: "production"},
+	}
+
+	tests := []struct {
+		Template string
+		Expected string
+	}{
+		{
+			Template: `{{ range . }}{{ .SerialNumber }} {{ .Phase }}{{ "\n" }}{{ end }}`,
+			Expected: "abc123 integration\ndef456 production\n",
+		},
+		{
+			Template: `{{ range . }}{{ .Phase | upper }} {{ .Hostname | default "-" | truncate 4 }}{{ "\n" }}{{ end }}`,
+			Expected: "INTEGRATION sw-1\nPRODUCTION -\n",
+		},
+		{
+			Template: `{{ range . }}{{ .SerialNumber | json }} {{ end }}`,
+			Expected: `"abc123" "def456" `,
+		},
+		{
+			Template: `<{{ (index . 0).Links | default "no links" }}>`,
+			Expected: "<no links>",
+		},
+	}
+
+	for _, test := range tests {
+		buf := &bytes.Buffer{}
+		config := cli.NewConfig("test", "test")
+		config.Template = test.Template
+		config.RenderTo(buf)(devices, nil)
+		assert.Equal(t, test.Expected, buf.String(), test.Template)
+	}
+}
@@ -0,0 +1,312 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	cli "github.com/jawher/mow.cli"
+	"gopkg.in/yaml.v2"
+)
+
+// Profile is a named set of connection and output settings stored in the
+// kosh config file
+type Profile struct {
+	Name  string `yaml:"-" json:"name"`
+	URL   string `yaml:"url" json:"url"`
+	Token string `yaml:"token,omitempty" json:"-"`
+	// TokenName is the server side name of Token, recorded by 'kosh login'
+	// so 'kosh logout' can delete it
+	TokenName string `yaml:"token_name,omitempty" json:"token_name,omitempty"`
+	// TokenCommand is used to get a token when Token isn't set
+	TokenCommand string `yaml:"token_command,omitempty" json:"token_command,omitempty"`
+	Output       string `yaml:"output,omitempty" json:"output,omitempty"`
+	Verbose      bool   `yaml:"verbose,omitempty" json:"verbose,omitempty"`
+	Debug        bool   `yaml:"debug,omitempty" json:"debug,omitempty"`
+
+	// Current is true for the profile used when --profile isn't given
+	Current bool `yaml:"-" json:"current"`
+}
+
+const profileTemplate = `
+Profile {{ .Name }}
+===================
+
+URL: {{ .URL }}
+Token: {{ if .Token }}(set){{ else if .TokenCommand }}from {{ .TokenCommand }}{{ else }}(not set){{ end }}
+Output: {{ if .Output }}{{ .Output }}{{ else }}(default){{ end }}
+Verbose: {{ .Verbose }}
+Debug: {{ .Debug }}
+`
+
+// Template returns a template string for rendering to Markdown
+func (p Profile) Template() string { return profileTemplate }
+
+// Profiles is a slice of Profile structs
+type Profiles []Profile
+
+func (p Profiles) Len() int           { return len(p) }
+func (p Profiles) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p Profiles) Less(i, j int) bool { return p[i].Name < p[j].Name }
+
+// Headers returns the list of headers for the table view
+func (p Profiles) Headers() []string {
+	return []string{
+		"Current",
+		"Name",
+		"URL",
+		"Token",
+		"Output",
+	}
+}
+
+// ForEach iterates over each item in the list and applies a function to it
+func (p Profiles) ForEach(do func([]string)) {
+	for _, profile := range p {
+		token := "no"
+		if profile.Token != "" {
+			token = "yes"
+		} else if profile.TokenCommand != "" {
+			token = "command"
+		}
+		current := ""
+		if profile.Current {
+			current = "*"
+		}
+		do([]string{
+			current,
+			profile.Name,
+			profile.URL,
+			token,
+			profile.Output,
+		})
+	}
+}
+
+// ConfigFile is the on disk kosh configuration, a set of named profiles and
+// which of them is in use
+type ConfigFile struct {
+	Path     string             `yaml:"-"`
+	Current  string             `yaml:"current_profile,omitempty"`
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// DefaultConfigFilePath returns the location of the kosh config file:
+// $KOSH_CONFIG if set, otherwise kosh/config.yaml under $XDG_CONFIG_HOME
+// (~/.config by default)
+func DefaultConfigFilePath() string {
+	if path := os.Getenv("KOSH_CONFIG"); path != "" {
+		return path
+	}
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "kosh", "config.yaml")
+}
+
+// LoadConfigFile reads the config file at the given path. A missing file is
+// not an error, it results in an empty configuration.
+func LoadConfigFile(path string) (*ConfigFile, error) {
+	f := &ConfigFile{Path: path, Profiles: map[string]Profile{}}
+
+	data, e := ioutil.ReadFile(path)
+	if os.IsNotExist(e) {
+		return f, nil
+	}
+	if e != nil {
+		return f, e
+	}
+
+	if e := yaml.Unmarshal(data, f); e != nil {
+		return f, fmt.Errorf("problem reading config file %s: %s", path, e)
+	}
+	if f.Profiles == nil {
+		f.Profiles = map[string]Profile{}
+	}
+	for name, p := range f.Profiles {
+		p.Name = name
+		f.Profiles[name] = p
+	}
+	return f, nil
+}
+
+// Save writes the config file back to disk. The file may contain API tokens
+// so it is only readable by the current user.
+func (f *ConfigFile) Save() error {
+	if e := os.MkdirAll(filepath.Dir(f.Path), 0700); e != nil {
+		return e
+	}
+	data, e := yaml.Marshal(f)
+	if e != nil {
+		return e
+	}
+	if e := ioutil.WriteFile(f.Path, data, 0600); e != nil {
+		return e
+	}
+	// WriteFile doesn't change the mode of an existing file
+	return os.Chmod(f.Path, 0600)
+}
+
+// Profile returns the named profile, or the current profile if name is empty
+func (f *ConfigFile) Profile(name string) (Profile, error) {
+	if name == "" {
+		name = f.Current
+	}
+	if name == "" {
+		return Profile{}, errors.New("no profile selected, use 'kosh profile use NAME' or --profile")
+	}
+	p, ok := f.Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("no profile named '%s' in %s", name, f.Path)
+	}
+	return p, nil
+}
+
+// List returns all the profiles sorted by name
+func (f *ConfigFile) List() Profiles {
+	list := Profiles{}
+	for _, p := range f.Profiles {
+		p.Current = p.Name == f.Current
+		list = append(list, p)
+	}
+	sort.Sort(list)
+	return list
+}
+
+// ApplyProfile fills in any settings that weren't given on the command line
+// or in the environment from the given profile
+func (c *Config) ApplyProfile(p Profile) {
+	if c.ConchURL == "" {
+		c.ConchURL = p.URL
+	}
+	// a token or token command given on the command line beats both of the
+	// profile's
+	if c.ConchToken == "" && c.TokenCommand == "" {
+		c.ConchToken = p.Token
+		if c.ConchToken == "" {
+			c.TokenCommand = p.TokenCommand
+		}
+	}
+	if c.Output == "" && !c.OutputJSON {
+		c.Output = p.Output
+	}
+	if p.Verbose {
+		c.Logger.LevelInfo = true
+	}
+	if p.Debug {
+		c.Logger.LevelDebug = true
+	}
+}
+
+func profileCommandsCmd(cmd *cli.Cmd) {
+	var file *ConfigFile
+	var display Renderer
+
+	cmd.Before = func() {
+		var e error
+		file, e = LoadConfigFile(config.ConfigFile)
+		fatalIf(e)
+		display = config.Renderer()
+	}
+
+	list := func() { display(file.List(), nil) }
+
+	// default action is 'list'
+	cmd.Action = list
+
+	cmd.Command("list ls", "List the profiles in the config file", func(cmd *cli.Cmd) {
+		cmd.Action = list
+	})
+
+	cmd.Command("show get", "Show a single profile, by default the current one", func(cmd *cli.Cmd) {
+		nameArg := cmd.StringArg("NAME", "", "Name of the profile")
+		cmd.Spec = "[NAME]"
+		cmd.Action = func() {
+			name := *nameArg
+			if name == "" {
+				name = config.Profile
+			}
+			display(file.Profile(name))
+		}
+	})
+
+	cmd.Command("add create", "Add a new profile, or replace an existing one", func(cmd *cli.Cmd) {
+		var (
+			nameArg    = cmd.StringArg("NAME", "", "Name of the profile")
+			urlOpt     = cmd.StringOpt("url", "", "URL of the Conch API")
+			envOpt     = cmd.StringOpt("env", "", "Use the URL of a known environment (production, staging) instead of --url")
+			tokenOpt   = cmd.StringOpt("token", "", "API token")
+			tokenCmd   = cmd.StringOpt("token-command", "", "Command that prints an API token, used instead of --token")
+			outputOpt  = cmd.StringOpt("output", "", "Default output format. One of: "+strings.Join(outputList, ", "))
+			verboseOpt = cmd.BoolOpt("verbose", false, "Enable verbose output")
+			debugOpt   = cmd.BoolOpt("debug", false, "Enable debugging output")
+			useOpt     = cmd.BoolOpt("use", false, "Make this the current profile")
+		)
+		cmd.Spec = "NAME (--url | --env) [OPTIONS]"
+
+		cmd.Action = func() {
+			url := *urlOpt
+			if *envOpt != "" {
+				var e error
+				url, e = envURL(*envOpt)
+				fatalIf(e)
+			}
+			if url == "" {
+				fatalIf(errUsage("--url or --env is required"))
+			}
+			if !okOutput(*outputOpt) {
+				fatalIf(errUsage("--output must be one of: %s", strings.Join(outputList, ", ")))
+			}
+
+			file.Profiles[*nameArg] = Profile{
+				Name:         *nameArg,
+				URL:          url,
+				Token:        *tokenOpt,
+				TokenCommand: *tokenCmd,
+				Output:       *outputOpt,
+				Verbose:      *verboseOpt,
+				Debug:        *debugOpt,
+			}
+			if *useOpt || len(file.Profiles) == 1 {
+				file.Current = *nameArg
+			}
+			fatalIf(file.Save())
+			list()
+		}
+	})
+
+	cmd.Command("use", "Make the named profile the current one", func(cmd *cli.Cmd) {
+		nameArg := cmd.StringArg("NAME", "", "Name of the profile")
+		cmd.Spec = "NAME"
+		cmd.Action = func() {
+			_, e := file.Profile(*nameArg)
+			fatalIf(e)
+
+			file.Current = *nameArg
+			fatalIf(file.Save())
+			fmt.Printf("Now using profile '%s'\n", *nameArg)
+		}
+	})
+
+	cmd.Command("remove rm", "Remove a profile", func(cmd *cli.Cmd) {
+		nameArg := cmd.StringArg("NAME", "", "Name of the profile")
+		cmd.Spec = "NAME"
+		cmd.Action = func() {
+			_, e := file.Profile(*nameArg)
+			fatalIf(e)
+
+			delete(file.Profiles, *nameArg)
+			if file.Current == *nameArg {
+				file.Current = ""
+			}
+			fatalIf(file.Save())
+			list()
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package cli_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/joyent/kosh/cli"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kosh")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "kosh", "config.yaml")
+
+	t.Run("missing file is empty", func(t *testing.T) {
+		f, err := cli.LoadConfigFile(path)
+		assert.Nil(t, err)
+		assert.Empty(t, f.Profiles)
+		_, err = f.Profile("")
+		assert.Error(t, err)
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		f, _ := cli.LoadConfigFile(path)
+		f.Profiles["local"] = cli.Profile{URL: "http://localhost:5000", Token: "secret", Output: "json"}
+		f.Current = "local"
+		assert.Nil(t, f.Save())
+
+		info, err := os.Stat(path)
+		assert.Nil(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+		f, err = cli.LoadConfigFile(path)
+		assert.Nil(t, err)
+		p, err := f.Profile("")
+		assert.Nil(t, err)
+		assert.Equal(t, "local", p.Name)
+		assert.Equal(t, "secret", p.Token)
+
+		_, err = f.Profile("nope")
+		assert.Error(t, err)
+	})
+}
+
+func TestApplyProfile(t *testing.T) {
+	p := cli.Profile{URL: "http://localhost:5000", Token: "secret", Output: "json", Verbose: true}
+
+	config := cli.NewConfig("test", "test")
+	config.ApplyProfile(p)
+	assert.Equal(t, p.URL, config.ConchURL)
+	assert.Equal(t, p.Token, config.ConchToken)
+	assert.Equal(t, "json", config.OutputFormat())
+	assert.True(t, config.Logger.LevelInfo)
+
+	config = cli.NewConfig("test", "test")
+	config.ConchToken = "from-flag"
+	config.ApplyProfile(p)
+	assert.Equal(t, "from-flag", config.ConchToken)
+}
@@ -0,0 +1,258 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/joyent/kosh/conch"
+	"github.com/joyent/kosh/conch/types"
+)
+
+// assignSlot is a slot of the rack layout and the device currently assigned
+// to it, if any
+type assignSlot struct {
+	RackUnitStart int
+	RackUnitSize  int
+	SKU           string
+	SerialNumber  string
+	AssetTag      string
+}
+
+func (s assignSlot) units() string {
+	if s.RackUnitSize > 1 {
+		return fmt.Sprintf("%d-%d", s.RackUnitStart, s.RackUnitStart+s.RackUnitSize-1)
+	}
+	return fmt.Sprintf("%d", s.RackUnitStart)
+}
+
+func (s assignSlot) current() string {
+	if s.SerialNumber == "" {
+		return "unassigned"
+	}
+	if s.AssetTag == "" {
+		return s.SerialNumber
+	}
+	return fmt.Sprintf("%s (asset tag %s)", s.SerialNumber, s.AssetTag)
+}
+
+// getAssignSlots returns the slots of the rack layout from the top of the
+// rack down, with their current assignments
+func getAssignSlots(c *conch.Client, rack types.UUID) ([]assignSlot, error) {
+	layout, e := c.GetRackLayout(rack)
+	if e != nil {
+		return nil, e
+	}
+	assignments, e := c.GetRackAssignments(rack)
+	if e != nil {
+		return nil, e
+	}
+
+	assigned := map[int]types.RackAssignment{}
+	for _, a := range assignments {
+		assigned[int(a.RackUnitStart)] = a
+	}
+
+	slots := make([]assignSlot, 0, len(layout))
+	for _, l := range layout {
+		slot := assignSlot{
+			RackUnitStart: int(l.RackUnitStart),
+			RackUnitSize:  int(l.RackUnitSize),
+			SKU:           string(l.Sku),
+		}
+		if a, ok := assigned[slot.RackUnitStart]; ok {
+			slot.SerialNumber = string(a.DeviceSerialNumber)
+			slot.AssetTag = string(a.DeviceAssetTag)
+		}
+		slots = append(slots, slot)
+	}
+
+	sort.Slice(slots, func(i, j int) bool { return slots[i].RackUnitStart > slots[j].RackUnitStart })
+	return slots, nil
+}
+
+// assignChange is a device to assign to a slot
+type assignChange struct {
+	slot         assignSlot
+	SerialNumber string
+	AssetTag     string
+}
+
+// assignChanges are the devices to assign to the slots of a rack
+type assignChanges []assignChange
+
+func (c assignChanges) Len() int      { return len(c) }
+func (c assignChanges) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+func (c assignChanges) Less(i, j int) bool {
+	return c[i].slot.RackUnitStart > c[j].slot.RackUnitStart
+}
+
+// Headers returns the list of headers for the table view
+func (c assignChanges) Headers() []string {
+	return []string{
+		"RU",
+		"SKU",
+		"Current",
+		"Serial Number",
+		"Asset Tag",
+	}
+}
+
+// ForEach iterates over each item in the list and applies a function to it
+func (c assignChanges) ForEach(do func([]string)) {
+	for _, change := range c {
+		do([]string{
+			change.slot.units(),
+			change.slot.SKU,
+			change.slot.current(),
+			change.SerialNumber,
+			change.AssetTag,
+		})
+	}
+}
+
+// update turns the changes into the request for the rack
+func (c assignChanges) update() types.RackAssignmentUpdates {
+	update := make(types.RackAssignmentUpdates, 0, len(c))
+	for _, change := range c {
+		u := types.RackAssignmentUpdate{
+			DeviceSerialNumber: types.DeviceSerialNumber(change.SerialNumber),
+			RackUnitStart:      types.PositiveInteger(change.slot.RackUnitStart),
+		}
+		if change.AssetTag != "" {
+			u.DeviceAssetTag = change.AssetTag
+		}
+		update = append(update, u)
+	}
+	return update
+}
+
+// add records the device for the slot, unless it's already assigned there.
+// Each serial number and slot can only be given once, seen tracks what has
+// been given so far, including devices left where they are.
+func (c *assignChanges) add(seen map[string]assignSlot, slot assignSlot, serial, assetTag string) error {
+	if other, ok := seen[serial]; ok {
+		return fmt.Errorf("%s was already given for RU %s", serial, other.units())
+	}
+	for other, s := range seen {
+		if s.RackUnitStart == slot.RackUnitStart {
+			return fmt.Errorf("RU %s was already given %s", slot.units(), other)
+		}
+	}
+	seen[serial] = slot
+
+	if serial == slot.SerialNumber && assetTag == slot.AssetTag {
+		return nil
+	}
+	*c = append(*c, assignChange{slot, serial, assetTag})
+	return nil
+}
+
+// promptAssignments walks the slots asking for the serial number and asset
+// tag of the device in each. Leaving the serial number blank keeps what is
+// there already, the end of input stops early with what has been entered.
+func promptAssignments(in *bufio.Reader, slots []assignSlot) (assignChanges, error) {
+	var changes assignChanges
+	seen := map[string]assignSlot{}
+
+	ask := func(question, current string) (string, bool, error) {
+		if current != "" {
+			question = fmt.Sprintf("%s [%s]", question, current)
+		}
+		answer, e := prompt(in, "  "+question+": ")
+		if e == io.EOF {
+			fmt.Fprintln(os.Stderr)
+			return "", true, nil
+		}
+		if answer == "" {
+			answer = current
+		}
+		return answer, false, e
+	}
+
+	for i := 0; i < len(slots); i++ {
+		slot := slots[i]
+		fmt.Fprintf(os.Stderr, "RU %s  %s  %s\n", slot.units(), slot.SKU, slot.current())
+
+		serial, done, e := ask("Serial number", slot.SerialNumber)
+		if e != nil || done {
+			return changes, e
+		}
+		if serial == "" {
+			continue
+		}
+
+		current := ""
+		if serial == slot.SerialNumber {
+			current = slot.AssetTag
+		}
+		assetTag, done, e := ask("Asset tag", current)
+		if e != nil || done {
+			return changes, e
+		}
+
+		if e := changes.add(seen, slot, serial, assetTag); e != nil {
+			fmt.Fprintf(os.Stderr, "  %s, try again\n", e)
+			i--
+		}
+	}
+	return changes, nil
+}
+
+// readAssignmentCSV reads rows of ru,serial,asset_tag for the slots, the
+// asset tag is optional and a header row is skipped
+func readAssignmentCSV(r io.Reader, slots []assignSlot) (assignChanges, error) {
+	byRU := map[int]assignSlot{}
+	for _, slot := range slots {
+		byRU[slot.RackUnitStart] = slot
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var changes assignChanges
+	seen := map[string]assignSlot{}
+	for n := 1; ; n++ {
+		row, e := reader.Read()
+		if e == io.EOF {
+			break
+		}
+		if e != nil {
+			return nil, e
+		}
+		if len(row) < 2 || len(row) > 3 {
+			return nil, fmt.Errorf("row %d: expected ru,serial,asset_tag", n)
+		}
+
+		ru, e := strconv.Atoi(strings.TrimSpace(row[0]))
+		if e != nil {
+			if n == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("row %d: %q is not a rack unit", n, row[0])
+		}
+		slot, ok := byRU[ru]
+		if !ok {
+			return nil, fmt.Errorf("row %d: no slot in the rack layout starts at RU %d", n, ru)
+		}
+
+		serial := strings.TrimSpace(row[1])
+		if serial == "" {
+			continue
+		}
+		assetTag := ""
+		if len(row) == 3 {
+			assetTag = strings.TrimSpace(row[2])
+		}
+		if e := changes.add(seen, slot, serial, assetTag); e != nil {
+			return nil, fmt.Errorf("row %d: %s", n, e)
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testAssignSlots is a rack with a 2U slot at the top, a slot with a device
+// in the middle and an empty 1U slot at the bottom
+var testAssignSlots = []assignSlot{
+	{RackUnitStart: 5, RackUnitSize: 2, SKU: "2U"},
+	{RackUnitStart: 3, RackUnitSize: 1, SKU: "1U", SerialNumber: "OLD", AssetTag: "T1"},
+	{RackUnitStart: 1, RackUnitSize: 1, SKU: "1U"},
+}
+
+func TestReadAssignmentCSV(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Input    string
+		Expected assignChanges
+		Err      string
+	}{
+		{
+			Name:  "header and rows",
+			Input: "ru,serial,asset_tag\n5, SN5\n1,SN1,A1\n",
+			Expected: assignChanges{
+				{testAssignSlots[0], "SN5", ""},
+				{testAssignSlots[2], "SN1", "A1"},
+			},
+		},
+		{
+			Name:     "blank serials and unchanged devices are left out",
+			Input:    "5,\n3,OLD,T1\n",
+			Expected: nil,
+		},
+		{
+			Name:     "a new asset tag for the same device",
+			Input:    "3,OLD,T2\n",
+			Expected: assignChanges{{testAssignSlots[1], "OLD", "T2"}},
+		},
+		{
+			Name:  "moving a device",
+			Input: "3,SN3\n1,OLD,T1\n",
+			Expected: assignChanges{
+				{testAssignSlots[1], "SN3", ""},
+				{testAssignSlots[2], "OLD", "T1"},
+			},
+		},
+		{
+			Name:  "not a rack unit",
+			Input: "5,SN5\nsix,SN6\n",
+			Err:   `row 2: "six" is not a rack unit`,
+		},
+		{
+			Name:  "no such slot",
+			Input: "4,SN4\n",
+			Err:   "row 1: no slot in the rack layout starts at RU 4",
+		},
+		{
+			Name:  "wrong number of columns",
+			Input: "5,SN5,A,B\n",
+			Err:   "row 1: expected ru,serial,asset_tag",
+		},
+		{
+			Name:  "serial given twice",
+			Input: "5,SN5\n1,SN5\n",
+			Err:   "row 2: SN5 was already given for RU 5-6",
+		},
+		{
+			Name:  "slot given twice",
+			Input: "1,SN1\n1,SN2\n",
+			Err:   "row 2: RU 1 was already given SN1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			changes, err := readAssignmentCSV(strings.NewReader(test.Input), testAssignSlots)
+			if test.Err != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, test.Err, err.Error())
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, test.Expected, changes)
+		})
+	}
+}
+
+func TestPromptAssignments(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Input    string
+		Expected assignChanges
+	}{
+		{
+			Name: "every slot",
+			// serial and asset tag for each slot, top down
+			Input: "SN5\nA5\n\n\nSN1\n\n",
+			Expected: assignChanges{
+				{testAssignSlots[0], "SN5", "A5"},
+				{testAssignSlots[2], "SN1", ""},
+			},
+		},
+		{
+			Name:     "keeping the current device with a new asset tag",
+			Input:    "\nOLD\nT2\n\n",
+			Expected: assignChanges{{testAssignSlots[1], "OLD", "T2"}},
+		},
+		{
+			Name:  "the same serial twice is asked again",
+			Input: "SN5\n\nSN5\n\nSN3\n\n\n",
+			Expected: assignChanges{
+				{testAssignSlots[0], "SN5", ""},
+				{testAssignSlots[1], "SN3", ""},
+			},
+		},
+		{
+			Name:     "the end of input stops early",
+			Input:    "SN5\nA5\n",
+			Expected: assignChanges{{testAssignSlots[0], "SN5", "A5"}},
+		},
+		{
+			Name:     "nothing",
+			Input:    "",
+			Expected: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			changes, err := promptAssignments(bufio.NewReader(strings.NewReader(test.Input)), testAssignSlots)
+			assert.Nil(t, err)
+			assert.Equal(t, test.Expected, changes)
+		})
+	}
+}
+
+func TestAssignChangesUpdate(t *testing.T) {
+	changes := assignChanges{
+		{testAssignSlots[0], "SN5", ""},
+		{testAssignSlots[2], "SN1", "A1"},
+	}
+	update := changes.update()
+	assert.Len(t, update, 2)
+	assert.Equal(t, "SN5", string(update[0].DeviceSerialNumber))
+	assert.Equal(t, 5, int(update[0].RackUnitStart))
+	assert.Nil(t, update[0].DeviceAssetTag)
+	assert.Equal(t, "A1", update[1].DeviceAssetTag)
+}
@@ -1,8 +1,16 @@
 package cli
 
 import (
+	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
 
 	cli "github.com/jawher/mow.cli"
 	"github.com/joyent/kosh/conch"
@@ -40,36 +48,36 @@ func racksCmd(cmd *cli.Cmd) {
 			// `--name ""` which will pass the cli lib's requirement
 			// check but is still crap
 			if *nameOpt == "" {
-				fatalIf(errors.New("--name is required"))
+				fatalIf(errUsage("--name is required"))
 			}
 
 			if *roomAliasOpt == "" {
-				fatalIf(errors.New("--room is required"))
+				fatalIf(errUsage("--room is required"))
 			} else {
 				room, e := conch.GetRoomByAlias(*roomAliasOpt)
 				fatalIf(e)
 
 				if (room == types.DatacenterRoomDetailed{}) {
-					fatalIf(errors.New("could not find room"))
+					fatalIf(errNotFound("could not find room"))
 				}
 				roomID = room.ID
 			}
 
 			if *roleNameOpt == "" {
-				fatalIf(errors.New("--role is required"))
+				fatalIf(errUsage("--role is required"))
 			} else {
 				role, e := conch.GetRackRoleByName(*roleNameOpt)
 				if e != nil {
 					fatalIf(e)
 				}
 				if (role == types.RackRole{}) {
-					fatalIf(errors.New("could not find rack role"))
+					fatalIf(errNotFound("could not find rack role"))
 				}
 				roleID = role.ID
 			}
 
 			if *buildNameOpt == "" {
-				fatalIf(errors.New("--build is required"))
+				fatalIf(errUsage("--build is required"))
 			} else {
 				build, e := conch.GetBuildByName(*buildNameOpt)
 				if e != nil {
@@ -108,33 +116,54 @@ func rackCmd(cmd *cli.Cmd) {
 
 	var rack types.Rack
 
+	// requireRack is the Before of every command but 'layout lint', which can
+	// check a file against a rack role without a rack
+	requireRack := func() {
+		if rack.ID == (types.UUID{}) {
+			fatalIf(errUsage("the rack UUID is required"))
+		}
+	}
+
 	idArg := cmd.StringArg(
 		"UUID",
 		"",
 		"The UUID of the rack.",
 	)
 
-	cmd.Spec = "UUID"
+	cmd.Spec = "[UUID]"
 
 	cmd.Before = func() {
 		config.requireAuth()
 		conch = config.ConchClient()
 		display = config.Renderer()
 
+		if *idArg == "" {
+			return
+		}
+
+		if r, ok := config.shell.contextRack(*idArg); ok {
+			rack = r
+			return
+		}
+
 		var e error
 		rack, e = conch.GetRackByName(*idArg)
 		fatalIf(e)
 
-		if (rack == types.Rack{}) {
-			fatalIf(errors.New("could not find the rack"))
+		if rack.ID == (types.UUID{}) {
+			fatalIf(errNotFound("could not find the rack"))
 		}
 	}
 
 	cmd.Command("get", "Get a single rack", func(cmd *cli.Cmd) {
+		cmd.Before = requireRack
+
 		cmd.Action = func() { display(rack, nil) }
 	})
 
 	cmd.Command("update", "Update information about a single rack", func(cmd *cli.Cmd) {
+		cmd.Before = requireRack
+
 		var (
 			nameOpt      = cmd.StringOpt("name", "", "Name of the rack")
 			roomAliasOpt = cmd.StringOpt("room", "", "Alias of the datacenter room")
@@ -162,7 +191,7 @@ func rackCmd(cmd *cli.Cmd) {
 					fatalIf(e)
 				}
 				if (room == types.DatacenterRoomDetailed{}) {
-					fatalIf(errors.New("could not find room"))
+					fatalIf(errNotFound("could not find room"))
 				}
 				roomID = room.ID
 			}
@@ -172,7 +201,7 @@ func rackCmd(cmd *cli.Cmd) {
 					fatalIf(e)
 				}
 				if (role == types.RackRole{}) {
-					fatalIf(errors.New("could not find rack role"))
+					fatalIf(errNotFound("could not find rack role"))
 				}
 				roleID = role.ID
 			}
@@ -195,40 +224,128 @@ func rackCmd(cmd *cli.Cmd) {
 				assetTag = &empty
 			}
 
-			conch.UpdateRack(rack.ID, types.RackUpdate{
+			fatalIf(conch.UpdateRack(rack.ID, types.RackUpdate{
 				Name:             types.MojoRelaxedPlaceholder(*nameOpt),
 				DatacenterRoomID: roomID,
 				RackRoleID:       roleID,
 				Phase:            types.DevicePhase(*phaseOpt),
 				SerialNumber:     serial,
 				AssetTag:         assetTag,
-			})
+			}))
 		}
 	})
 
 	cmd.Command("delete rm", "Delete a rack", func(cmd *cli.Cmd) {
 		cmd.Before = func() {
+			requireRack()
 			config.requireAuth()
 			config.requireSysAdmin()
 		}
 
 		cmd.Action = func() {
-			conch.DeleteRack(rack.ID)
+			fatalIf(conch.DeleteRack(rack.ID))
 			fmt.Println("OK")
 		}
 	})
 
+	cmd.Command("phase", "The lifecycle phase of the rack", func(cmd *cli.Cmd) {
+		cmd.Before = requireRack
+
+		// default action is 'get'
+		cmd.Action = func() { display(rack.Phase, nil) }
+
+		cmd.Command("get", "Get the phase of the rack", func(cmd *cli.Cmd) {
+			cmd.Action = func() { display(rack.Phase, nil) }
+		})
+
+		cmd.Command("set", "Set the phase of the rack and its devices [one of: "+prettyPhasesList()+"]", func(cmd *cli.Cmd) {
+			var (
+				phaseArg    = cmd.StringArg("PHASE", "", "Name of the phase [one of: "+prettyPhasesList()+"]")
+				rackOnlyOpt = cmd.BoolOpt("rack-only", false, "Only set the phase of the rack, not the devices in it")
+			)
+			cmd.Spec = "[--rack-only] PHASE"
+
+			cmd.Action = func() {
+				if !okPhase(*phaseArg) {
+					fatalIf(errUsage("phase must be one of: %s", prettyPhasesList()))
+				}
+				fatalIf(conch.UpdateRackPhase(
+					rack.ID,
+					types.RackPhase{Phase: types.DevicePhase(*phaseArg)},
+					*rackOnlyOpt,
+				))
+				r, e := conch.GetRackByID(rack.ID)
+				display(r.Phase, e)
+			}
+		})
+	})
+
+	cmd.Command("links", "The links of the rack", func(cmd *cli.Cmd) {
+		cmd.Before = requireRack
+
+		showLinks := func() {
+			l, e := conch.GetRackLinks(rack.ID)
+			display(l.Links, e)
+		}
+
+		// default action is 'ls'
+		cmd.Action = showLinks
+
+		cmd.Command("get ls", "Get the links of the rack", func(cmd *cli.Cmd) {
+			cmd.Action = showLinks
+		})
+
+		cmd.Command("add", "Add links to the rack", func(cmd *cli.Cmd) {
+			urlsArg := cmd.StringsArg("URL", nil, "URLs to add")
+			cmd.Spec = "URL..."
+
+			cmd.Action = func() {
+				links, e := rackLinks(*urlsArg)
+				fatalIf(e)
+				fatalIf(conch.UpdateRackLinks(rack.ID, links))
+				l, e := conch.GetRackLinks(rack.ID)
+				display(l.Links, e)
+			}
+		})
+
+		cmd.Command("rm delete", "Remove links from the rack", func(cmd *cli.Cmd) {
+			var (
+				allOpt  = cmd.BoolOpt("all", false, "Remove all of the links")
+				urlsArg = cmd.StringsArg("URL", nil, "URLs to remove")
+			)
+			cmd.Spec = "[--all] [URL...]"
+
+			cmd.Action = func() {
+				if *allOpt == (len(*urlsArg) > 0) {
+					fatalIf(errUsage("give either the URLs to remove or --all"))
+				}
+				links, e := rackLinks(*urlsArg)
+				fatalIf(e)
+				fatalIf(conch.DeleteRackLinks(rack.ID, links))
+				l, e := conch.GetRackLinks(rack.ID)
+				display(l.Links, e)
+			}
+		})
+	})
+
 	cmd.Command("layout", "The layout of the rack", func(cmd *cli.Cmd) {
 		// default action is 'get'
-		cmd.Action = func() { display(conch.GetRackLayout(rack.ID)) }
+		cmd.Action = func() {
+			requireRack()
+			display(conch.GetRackLayout(rack.ID))
+		}
 
 		cmd.Command("get", "Get the layout of a rack", func(cmd *cli.Cmd) {
+			cmd.Before = requireRack
+
 			cmd.Action = func() {
 				display(conch.GetRackLayout(rack.ID))
 			}
 		})
 
 		cmd.Command("export", "Export the layout of the rack as JSON", func(cmd *cli.Cmd) {
+			cmd.Before = requireRack
+
 			cmd.Action = func() {
 				l, e := conch.GetRackLayout(rack.ID)
 				if e != nil {
@@ -238,9 +355,112 @@ func rackCmd(cmd *cli.Cmd) {
 			}
 		})
 
+		cmd.Command("copy", "Copy the layout of this rack to other racks", func(cmd *cli.Cmd) {
+			cmd.Before = requireRack
+
+			var (
+				toOpt        = cmd.StringsOpt("to", nil, "Name or UUID of a rack to copy the layout to, can be repeated")
+				roomOpt      = cmd.StringOpt("room", "", "Copy to the racks in the datacenter room with this alias...")
+				roleOpt      = cmd.StringOpt("role", "", "...that have the rack role with this name")
+				overwriteOpt = cmd.BoolOpt("overwrite", false, "Replace the layout of racks that already have one, instead of skipping them")
+				parallelOpt  = cmd.IntOpt("parallel", 4, "Number of racks to update at the same time")
+			)
+			cmd.Spec = "[--to]... [--room --role] [--overwrite] [--parallel]"
+
+			cmd.Action = func() {
+				if (len(*toOpt) == 0) == (*roomOpt == "") {
+					fatalIf(errUsage("give either --to or --room and --role"))
+				}
+
+				layout, e := conch.GetRackLayout(rack.ID)
+				fatalIf(e)
+				if len(layout) == 0 {
+					fatalIf(fmt.Errorf("rack %s has no layout to copy", rack.Name))
+				}
+
+				update := make([]types.RackLayoutUpdate, 0, len(layout))
+				for _, slot := range layout {
+					update = append(update, types.RackLayoutUpdate{
+						HardwareProductID: slot.HardwareProductID,
+						RackUnitStart:     slot.RackUnitStart,
+					})
+				}
+
+				var targets []layoutCopyTarget
+				for _, name := range *toOpt {
+					name := name
+					targets = append(targets, layoutCopyTarget{
+						name: name,
+						find: func() (types.Rack, error) {
+							r, e := conch.GetRackByName(name)
+							if e == nil && r.ID == (types.UUID{}) {
+								e = errNotFound("could not find the rack")
+							}
+							return r, e
+						},
+					})
+				}
+
+				if *roomOpt != "" {
+					room, e := conch.GetRoomByAlias(*roomOpt)
+					fatalIf(e)
+					if (room == types.DatacenterRoomDetailed{}) {
+						fatalIf(errNotFound("could not find room"))
+					}
+
+					racks, e := conch.GetAllRoomRacks(room.ID)
+					fatalIf(e)
+					for _, r := range racks {
+						if string(r.RackRoleName) != *roleOpt {
+							continue
+						}
+						r := r
+						targets = append(targets, layoutCopyTarget{
+							name: string(r.Name),
+							find: func() (types.Rack, error) { return r, nil },
+						})
+					}
+					if len(targets) == 0 {
+						fatalIf(errNotFound("no racks in room %s have the rack role %s", *roomOpt, *roleOpt))
+					}
+				}
+
+				results := copyRackLayout(conch, rack.ID, update, targets, *overwriteOpt, *parallelOpt)
+				display(results, nil)
+
+				if n := results.failed(); n > 0 {
+					fatalIf(fmt.Errorf("copying the layout failed for %d of %d racks", n, len(results)))
+				}
+			}
+		})
+
+		cmd.Command("lint", "Check a layout file for problems before importing it", func(cmd *cli.Cmd) {
+			cmd.LongDesc = `Check a layout file against the hardware products and a rack role. Without a
+rack, e.g. 'kosh rack layout lint FILE --role NAME', the file is checked against
+the named role alone. With one, against the rack's role unless --role is given.`
+
+			filePathArg := cmd.StringArg("FILE", "-", "Path to a JSON file that defines the layout. '-' indicates STDIN")
+			roleOpt := cmd.StringOpt("role", "", "Name of the rack role to check against, instead of the rack's")
+			cmd.Spec = "[--role] [FILE] [--role]"
+
+			cmd.Action = func() {
+				input, e := getInputReader(*filePathArg)
+				fatalIf(e)
+				b, e := ioutil.ReadAll(input)
+				fatalIf(e)
+
+				_, e = lintLayoutFile(conch, rack, *roleOpt, *filePathArg, b)
+				fatalIf(e)
+				fmt.Println("OK")
+			}
+		})
+
 		cmd.Command("import", "Import the layout of this rack (using the same format as 'export')", func(cmd *cli.Cmd) {
+			cmd.Before = requireRack
+
 			filePathArg := cmd.StringArg("FILE", "-", "Path to a JSON file that defines the layout. '-' indicates STDIN")
 			overwriteOpt := cmd.BoolOpt("overwrite", false, "If the rack has an existing layout, *overwrite* it. This is a destructive action")
+			noLintOpt := cmd.BoolOpt("no-lint", false, "Send the layout without checking it with 'layout lint' first")
 
 			cmd.Action = func() {
 				layout, e := conch.GetRackLayout(rack.ID)
@@ -254,34 +474,262 @@ func rackCmd(cmd *cli.Cmd) {
 
 				input, e := getInputReader(*filePathArg)
 				fatalIf(e)
+				b, e := ioutil.ReadAll(input)
+				fatalIf(e)
 
-				update, e := conch.ReadRackLayoutUpdate(input)
-				if e != nil {
-					fatalIf(fmt.Errorf("problem reading Rack Layout: %s", e))
+				var update []types.RackLayoutUpdate
+				if *noLintOpt {
+					update, e = conch.ReadRackLayoutUpdate(bytes.NewReader(b))
+					if e != nil {
+						fatalIf(fmt.Errorf("problem reading Rack Layout: %s", e))
+					}
+				} else {
+					entries, e := lintLayoutFile(conch, rack, "", *filePathArg, b)
+					fatalIf(e)
+					update = layoutUpdate(entries)
 				}
 
-				conch.UpdateRackLayout(rack.ID, update)
+				fatalIf(conch.UpdateRackLayout(rack.ID, update))
 				fmt.Println("OK")
 			}
 		})
 	})
 
-	cmd.Command("assign", "Assign devices to rack slots, using the `--json` output from 'assignments'", func(cmd *cli.Cmd) {
-		filePathArg := cmd.StringArg("FILE", "-", "Path to a JSON file to use as the data source. '-' indicates STDIN")
+	cmd.Command("assign", "Assign devices to rack slots, using the `--json` output from 'assignments', a CSV file or prompts", func(cmd *cli.Cmd) {
+		cmd.Before = requireRack
+
+		var (
+			interactiveOpt = cmd.BoolOpt("interactive i", false, "Prompt for the device in each slot, from the top of the rack down")
+			csvOpt         = cmd.BoolOpt("csv", false, "Read the file as CSV with the columns ru,serial,asset_tag")
+			filePathArg    = cmd.StringArg("FILE", "-", "Path to a JSON file to use as the data source. '-' indicates STDIN")
+		)
+		cmd.Spec = "[--interactive | --csv] [FILE]"
+
 		cmd.Action = func() {
-			input, err := getInputReader(*filePathArg)
-			if err != nil {
-				fatalIf(err)
+			if !*interactiveOpt && !*csvOpt {
+				input, err := getInputReader(*filePathArg)
+				if err != nil {
+					fatalIf(err)
+				}
+				update, e := conch.ReadRackAssignmentUpdate(input)
+				fatalIf(e)
+				fatalIf(conch.UpdateRackAssignments(rack.ID, update))
+				return
 			}
-			update, e := conch.ReadRackAssignmentUpdate(input)
+
+			slots, e := getAssignSlots(conch, rack.ID)
 			fatalIf(e)
-			conch.UpdateRackAssignments(rack.ID, update)
+			if len(slots) == 0 {
+				fatalIf(fmt.Errorf("rack %s has no layout to assign devices to", rack.Name))
+			}
+
+			var changes assignChanges
+			if *interactiveOpt {
+				in := bufio.NewReader(os.Stdin)
+				changes, e = promptAssignments(in, slots)
+				fatalIf(e)
+				if len(changes) == 0 {
+					fmt.Fprintln(os.Stderr, "Nothing to assign")
+					return
+				}
+
+				display(changes, nil)
+				answer, e := prompt(in, fmt.Sprintf("Assign %d devices to rack %s? [y/N]: ", len(changes), rack.Name))
+				if e != nil && e != io.EOF {
+					fatalIf(e)
+				}
+				if !strings.HasPrefix(strings.ToLower(answer), "y") {
+					fmt.Fprintln(os.Stderr, "Nothing assigned")
+					return
+				}
+			} else {
+				input, e := getInputReader(*filePathArg)
+				fatalIf(e)
+				changes, e = readAssignmentCSV(input, slots)
+				fatalIf(e)
+				if len(changes) == 0 {
+					fmt.Fprintln(os.Stderr, "Nothing to assign")
+					return
+				}
+				display(changes, nil)
+			}
+
+			fatalIf(conch.UpdateRackAssignments(rack.ID, changes.update()))
+			fmt.Println("OK")
 		}
 	})
 
 	cmd.Command("assignments", "The devices assigned to the rack", func(cmd *cli.Cmd) {
+		cmd.Before = requireRack
+
+		// default action is 'get'
 		cmd.Action = func() {
 			display(conch.GetRackAssignments(rack.ID))
 		}
+
+		cmd.Command("get ls", "Get the devices assigned to the rack", func(cmd *cli.Cmd) {
+			cmd.Action = func() {
+				display(conch.GetRackAssignments(rack.ID))
+			}
+		})
+
+		cmd.Command("rm delete", "Remove a device from its slot in the rack", func(cmd *cli.Cmd) {
+			var (
+				ruOpt     = cmd.IntOpt("ru", 0, "Rack unit the slot starts at")
+				serialArg = cmd.StringArg("SERIAL", "", "Serial number of the device")
+			)
+			cmd.Spec = "[--ru] [SERIAL]"
+
+			cmd.Action = func() {
+				if (*ruOpt > 0) == (*serialArg != "") {
+					fatalIf(errUsage("give either the SERIAL of the device or the --ru of its slot"))
+				}
+
+				assignments, e := conch.GetRackAssignments(rack.ID)
+				fatalIf(e)
+
+				var found *types.RackAssignment
+				for i, a := range assignments {
+					if a.DeviceSerialNumber == "" {
+						continue
+					}
+					if string(a.DeviceSerialNumber) == *serialArg || int(a.RackUnitStart) == *ruOpt {
+						found = &assignments[i]
+						break
+					}
+				}
+				if found == nil {
+					if *serialArg != "" {
+						fatalIf(errNotFound("device %s is not assigned to rack %s", *serialArg, rack.Name))
+					}
+					fatalIf(errNotFound("no device is assigned to RU %d of rack %s", *ruOpt, rack.Name))
+				}
+
+				fatalIf(conch.DeleteRackAssignments(rack.ID, types.RackAssignmentDeletes{{
+					DeviceID:      found.DeviceID,
+					RackUnitStart: found.RackUnitStart,
+				}}))
+				fmt.Println("OK")
+			}
+		})
+	})
+
+	cmd.Command("slot", "A single slot of the rack layout", func(cmd *cli.Cmd) {
+		var slot types.RackLayout
+
+		ruArg := cmd.IntArg("RU", 0, "Rack unit the slot starts at")
+		cmd.Spec = "RU"
+
+		cmd.Before = func() {
+			requireRack()
+			if *ruArg < 1 {
+				fatalIf(errUsage("RU must be 1 or more"))
+			}
+			var e error
+			slot, e = getRackSlot(conch, rack, *ruArg)
+			fatalIf(e)
+		}
+
+		// default action is 'get'
+		cmd.Action = func() { display(types.RackLayouts{slot}, nil) }
+
+		cmd.Command("get", "Get the slot", func(cmd *cli.Cmd) {
+			cmd.Action = func() { display(types.RackLayouts{slot}, nil) }
+		})
+
+		cmd.Command("set", "Change the hardware product in the slot, or move it", func(cmd *cli.Cmd) {
+			var (
+				productOpt = cmd.StringOpt("product", "", "SKU or UUID of the hardware product")
+				moveOpt    = cmd.IntOpt("move-to", 0, "Rack unit to move the slot to")
+			)
+			cmd.Spec = "[--product] [--move-to]"
+
+			cmd.Action = func() {
+				if *productOpt == "" && *moveOpt == 0 {
+					fatalIf(errUsage("give --product, --move-to or both"))
+				}
+
+				if *moveOpt < 0 {
+					fatalIf(errUsage("--move-to must be 1 or more"))
+				}
+				l, e := setRackSlot(conch, rack, slot, *productOpt, *moveOpt)
+				display(types.RackLayouts{l}, e)
+			}
+		})
+
+		cmd.Command("rm delete", "Remove the slot from the rack layout", func(cmd *cli.Cmd) {
+			cmd.Action = func() {
+				fatalIf(conch.DeleteSingleRackLayout(rack.ID, slot.ID))
+				fmt.Println("OK")
+			}
+		})
 	})
+
+	cmd.Command("elevation", "Draw the rack from top to bottom, with the device in each slot", func(cmd *cli.Cmd) {
+		cmd.Before = requireRack
+
+		var (
+			svgOpt   = cmd.BoolOpt("svg", false, "Draw the rack as an SVG image instead, for printing")
+			asciiOpt = cmd.BoolOpt("ascii", false, "Draw with plain ASCII instead of box drawing characters")
+		)
+
+		cmd.Action = func() {
+			elevation, e := getRackElevation(conch, rack)
+			fatalIf(e)
+			elevation.ASCII = *asciiOpt
+
+			if *svgOpt {
+				fatalIf(elevation.SVG(os.Stdout))
+				return
+			}
+			display(elevation, nil)
+		}
+	})
+}
+
+// setRackSlot changes the hardware product of the slot, moves it to the rack
+// unit, or both. Whatever isn't given is kept as it is, the update replaces
+// the whole slot.
+func setRackSlot(c *conch.Client, rack types.Rack, slot types.RackLayout, product string, ru int) (types.RackLayout, error) {
+	update := types.RackLayoutUpdate{
+		HardwareProductID: slot.HardwareProductID,
+		RackUnitStart:     slot.RackUnitStart,
+	}
+	if product != "" {
+		p, e := c.GetHardwareProductByID(product)
+		if e != nil {
+			return types.RackLayout{}, e
+		}
+		update.HardwareProductID = p.ID
+	}
+	if ru != 0 {
+		update.RackUnitStart = types.PositiveInteger(ru)
+	}
+
+	if e := c.UpdateSingleRackLayout(rack.ID, slot.ID, update); e != nil {
+		return types.RackLayout{}, e
+	}
+	return c.GetSingleRackLayoutByID(rack.ID, slot.ID)
+}
+
+// getRackSlot gets the slot of the rack layout starting at the rack unit
+func getRackSlot(c *conch.Client, rack types.Rack, ru int) (types.RackLayout, error) {
+	slot, e := c.GetSingleRackLayoutByRU(rack.ID, strconv.Itoa(ru))
+	if conch.IsNotFound(e) {
+		e = errNotFound("rack %s has no slot starting at RU %d", rack.Name, ru)
+	}
+	return slot, e
+}
+
+// rackLinks checks that each link is an absolute URL
+func rackLinks(urls []string) (types.RackLinks, error) {
+	links := types.RackLinks{}
+	for _, u := range urls {
+		parsed, e := url.Parse(u)
+		if e != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return links, errUsage("%s is not a URL", u)
+		}
+		links.Links = append(links.Links, types.Link(u))
+	}
+	return links, nil
 }
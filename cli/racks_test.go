@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/joyent/kosh/conch"
+	"github.com/joyent/kosh/conch/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetRackSlot(t *testing.T) {
+	var (
+		rack = types.Rack{ID: testUUID("00000000-0000-0000-0000-0000000000a1")}
+		slot = types.RackLayout{
+			ID:                testUUID("00000000-0000-0000-0000-0000000000b1"),
+			HardwareProductID: testUUID("00000000-0000-0000-0000-000000000001"),
+			RackUnitStart:     3,
+		}
+		slotPath = "/rack/00000000-0000-0000-0000-0000000000a1/layout/00000000-0000-0000-0000-0000000000b1"
+	)
+
+	tests := []struct {
+		Name     string
+		Product  string
+		RU       int
+		Expected types.RackLayoutUpdate
+	}{
+		{
+			Name:     "move only keeps the product",
+			RU:       7,
+			Expected: types.RackLayoutUpdate{HardwareProductID: slot.HardwareProductID, RackUnitStart: 7},
+		},
+		{
+			Name:     "product only keeps the rack unit",
+			Product:  "2U",
+			Expected: types.RackLayoutUpdate{HardwareProductID: testUUID("00000000-0000-0000-0000-000000000002"), RackUnitStart: 3},
+		},
+		{
+			Name:     "both",
+			Product:  "2U",
+			RU:       1,
+			Expected: types.RackLayoutUpdate{HardwareProductID: testUUID("00000000-0000-0000-0000-000000000002"), RackUnitStart: 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			var (
+				requests []string
+				body     map[string]interface{}
+			)
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path := strings.TrimSuffix(r.URL.Path, "/")
+				requests = append(requests, r.Method+" "+path)
+				w.Header().Set("Content-Type", "application/json")
+				switch {
+				case path == "/hardware_product/2U":
+					w.Write([]byte(`{"id": "00000000-0000-0000-0000-000000000002", "sku": "2U"}`))
+				case path == slotPath && r.Method == http.MethodPost:
+					assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+					w.WriteHeader(http.StatusNoContent)
+				case path == slotPath:
+					w.Write([]byte(`{"id": "00000000-0000-0000-0000-0000000000b1", "rack_unit_start": 7}`))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer ts.Close()
+
+			l, err := setRackSlot(conch.New(conch.API(ts.URL)), rack, slot, test.Product, test.RU)
+			assert.Nil(t, err)
+			assert.Equal(t, slot.ID, l.ID)
+
+			expected := []string{"POST " + slotPath, "GET " + slotPath}
+			if test.Product != "" {
+				expected = append([]string{"GET /hardware_product/2U"}, expected...)
+			}
+			assert.Equal(t, expected, requests)
+
+			// the body has both fields, so the slot isn't cleared
+			assert.Equal(t, map[string]interface{}{
+				"hardware_product_id": test.Expected.HardwareProductID.String(),
+				"rack_unit_start":     float64(test.Expected.RackUnitStart),
+			}, body)
+		})
+	}
+}
@@ -1,8 +1,6 @@
 package cli
 
 import (
-	"errors"
-
 	cli "github.com/jawher/mow.cli"
 	"github.com/joyent/kosh/conch"
 	"github.com/joyent/kosh/conch/types"
@@ -47,7 +45,7 @@ func relayCmd(cmd *cli.Cmd) {
 			fatalIf(e)
 		}
 		if (relay == types.Relay{}) {
-			fatalIf(errors.New("relay not found"))
+			fatalIf(errNotFound("relay not found"))
 		}
 	}
 	// default action is to display the relay
@@ -66,18 +64,18 @@ func relayCmd(cmd *cli.Cmd) {
 		)
 
 		cmd.Action = func() {
-			conch.RegisterRelay(*relayArg, types.RegisterRelay{
+			fatalIf(conch.RegisterRelay(*relayArg, types.RegisterRelay{
 				Version: *versionOpt,
 				Ipaddr:  *ipAddrOpt,
 				Name:    types.NonEmptyString(*nameOpt),
 				SSHPort: types.NonNegativeInteger(*sshPortOpt),
-			})
+			}))
 		}
 	})
 
 	cmd.Command("delete rm", "Delete a relay", func(cmd *cli.Cmd) {
 		cmd.Action = func() {
-			conch.DeleteRelay(relay.ID.String())
+			fatalIf(conch.DeleteRelay(relay.ID.String()))
 			display(conch.GetAllRelays())
 		}
 	})
@@ -0,0 +1,280 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/joyent/kosh/conch/types"
+)
+
+// The kinds of difference between two reports
+const (
+	reportAdded   = "added"
+	reportRemoved = "removed"
+	reportChanged = "changed"
+)
+
+// reportComponents is the order components are listed in a diff
+var reportComponents = map[string]int{
+	"system":    0,
+	"disk":      1,
+	"dimm":      2,
+	"interface": 3,
+}
+
+// reportChange is a single difference between two device reports
+type reportChange struct {
+	Component string `json:"component"`
+	Key       string `json:"key"`
+	Change    string `json:"change"`
+	Field     string `json:"field,omitempty"`
+	Old       string `json:"old,omitempty"`
+	New       string `json:"new,omitempty"`
+}
+
+// reportDiff is every difference between two device reports
+type reportDiff []reportChange
+
+func (d reportDiff) Len() int      { return len(d) }
+func (d reportDiff) Swap(i, j int) { d[i], d[j] = d[j], d[i] }
+func (d reportDiff) Less(i, j int) bool {
+	if d[i].Component != d[j].Component {
+		return reportComponents[d[i].Component] < reportComponents[d[j].Component]
+	}
+	if d[i].Key != d[j].Key {
+		return d[i].Key < d[j].Key
+	}
+	return d[i].Field < d[j].Field
+}
+
+// Headers returns the list of headers for the table view
+func (d reportDiff) Headers() []string {
+	return []string{
+		"Component",
+		"Key",
+		"Change",
+		"Field",
+		"Old",
+		"New",
+	}
+}
+
+// ForEach iterates over each item in the list and applies a function to it
+func (d reportDiff) ForEach(do func([]string)) {
+	for _, c := range d {
+		do([]string{c.Component, c.Key, c.Change, c.Field, c.Old, c.New})
+	}
+}
+
+// toDeviceReport converts any of the shapes a device report comes in, a
+// stored report row or a report as sent by a reporter, to a DeviceReport
+func toDeviceReport(i interface{}) (types.DeviceReport, error) {
+	var report types.DeviceReport
+	b, e := json.Marshal(i)
+	if e != nil {
+		return report, e
+	}
+	e = json.Unmarshal(b, &report)
+	return report, e
+}
+
+// readDeviceReport reads a device report from a file, either the report
+// itself or a stored report row with the report under "report"
+func readDeviceReport(r io.Reader) (types.DeviceReport, error) {
+	var raw map[string]json.RawMessage
+	if e := json.NewDecoder(r).Decode(&raw); e != nil {
+		return types.DeviceReport{}, e
+	}
+	if inner, ok := raw["report"]; ok {
+		var report types.DeviceReport
+		e := json.NewDecoder(bytes.NewReader(inner)).Decode(&report)
+		return report, e
+	}
+	return toDeviceReport(raw)
+}
+
+// diffFields is a component as a set of named fields to compare
+type diffFields map[string]string
+
+// diffComponents compares two sets of components keyed the same way, each
+// with the same fields
+func diffComponents(component string, old, new map[string]diffFields) reportDiff {
+	var diff reportDiff
+	for key, o := range old {
+		n, ok := new[key]
+		if !ok {
+			diff = append(diff, reportChange{
+				Component: component,
+				Key:       key,
+				Change:    reportRemoved,
+				Old:       o.describe(),
+			})
+			continue
+		}
+		for field, value := range o {
+			if n[field] != value {
+				diff = append(diff, reportChange{
+					Component: component,
+					Key:       key,
+					Change:    reportChanged,
+					Field:     field,
+					Old:       value,
+					New:       n[field],
+				})
+			}
+		}
+	}
+	for key, n := range new {
+		if _, ok := old[key]; !ok {
+			diff = append(diff, reportChange{
+				Component: component,
+				Key:       key,
+				Change:    reportAdded,
+				New:       n.describe(),
+			})
+		}
+	}
+	return diff
+}
+
+// describe lists the non empty fields, in a stable order
+func (f diffFields) describe() string {
+	var keys []string
+	for k, v := range f {
+		if v != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var b bytes.Buffer
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%s=%s", k, f[k])
+	}
+	return b.String()
+}
+
+// diffDeviceReports compares two reports component by component. Reports of
+// the same device match disks by serial number and interfaces by name and
+// MAC. Reports of different devices match disks by their location instead,
+// and leave out serial numbers and MACs, which always differ.
+func diffDeviceReports(old, new types.DeviceReport, sameDevice bool) reportDiff {
+	var diff reportDiff
+
+	system := func(r types.DeviceReport) map[string]diffFields {
+		return map[string]diffFields{"device": {
+			"bios_version": r.BiosVersion,
+			"sku":          r.Sku,
+			"product_name": r.ProductName,
+			"cpus":         fmt.Sprintf("%d", len(r.Cpus)),
+		}}
+	}
+	diff = append(diff, diffComponents("system", system(old), system(new))...)
+
+	disks := func(r types.DeviceReport) map[string]diffFields {
+		m := map[string]diffFields{}
+		for serial, d := range r.Disks {
+			fields := diffFields{
+				"enclosure":  jsonCell(d.Enclosure),
+				"slot":       jsonCell(d.Slot),
+				"model":      d.Model,
+				"vendor":     d.Vendor,
+				"firmware":   d.Firmware,
+				"health":     d.Health,
+				"size":       "",
+				"drive_type": d.DriveType,
+			}
+			if d.Size > 0 {
+				fields["size"] = fmt.Sprintf("%d", d.Size)
+			}
+			key := serial
+			if !sameDevice {
+				key = fmt.Sprintf("enclosure %s slot %s", fields["enclosure"], fields["slot"])
+				delete(fields, "enclosure")
+				delete(fields, "slot")
+			}
+			m[key] = fields
+		}
+		return m
+	}
+	diff = append(diff, diffComponents("disk", disks(old), disks(new))...)
+
+	dimms := func(r types.DeviceReport) map[string]diffFields {
+		m := map[string]diffFields{}
+		for _, d := range r.Dimms {
+			fields := diffFields{"size": jsonCell(d.MemorySize)}
+			if sameDevice {
+				fields["serial_number"] = jsonCell(d.MemorySerialNumber)
+			}
+			m[d.MemoryLocator] = fields
+		}
+		return m
+	}
+	diff = append(diff, diffComponents("dimm", dimms(old), dimms(new))...)
+
+	interfaces := func(r types.DeviceReport) map[string]diffFields {
+		m := map[string]diffFields{}
+		for name, i := range r.Interfaces {
+			fields := diffFields{
+				"product": i.Product,
+				"vendor":  i.Vendor,
+			}
+			if sameDevice {
+				fields["mac"] = string(i.Mac)
+				fields["peer_mac"] = jsonCell(i.PeerMac)
+			}
+			m[name] = fields
+		}
+		return m
+	}
+	changes := diffComponents("interface", interfaces(old), interfaces(new))
+
+	// an interface that was removed and added again under a new name is the
+	// same card, renamed
+	if sameDevice {
+		renamed := map[string]string{}
+		newNames := map[string]bool{}
+		for name, i := range old.Interfaces {
+			if _, ok := new.Interfaces[name]; ok {
+				continue
+			}
+			for newName, j := range new.Interfaces {
+				if _, ok := old.Interfaces[newName]; !ok && i.Mac != "" && i.Mac == j.Mac {
+					renamed[name] = newName
+					newNames[newName] = true
+				}
+			}
+		}
+		var kept reportDiff
+		for _, c := range changes {
+			if _, ok := renamed[c.Key]; ok && c.Change == reportRemoved {
+				continue
+			}
+			if newNames[c.Key] && c.Change == reportAdded {
+				continue
+			}
+			kept = append(kept, c)
+		}
+		for name, newName := range renamed {
+			kept = append(kept, reportChange{
+				Component: "interface",
+				Key:       string(old.Interfaces[name].Mac),
+				Change:    reportChanged,
+				Field:     "name",
+				Old:       name,
+				New:       newName,
+			})
+		}
+		changes = kept
+	}
+	diff = append(diff, changes...)
+
+	sort.Sort(diff)
+	return diff
+}
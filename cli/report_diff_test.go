@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/joyent/kosh/conch/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// testDiffReport is a small report to compare changed copies of against
+const testDiffReport = `{
+	"serial_number": "SN1",
+	"bios_version": "1.0",
+	"sku": "600-0001",
+	"product_name": "Joyent-S10G5",
+	"cpus": [{}, {}],
+	"disks": {
+		"D1": {"enclosure": 0, "slot": 1, "vendor": "HGST", "model": "HUH721212AL", "firmware": "A1", "size": 100},
+		"D2": {"enclosure": 0, "slot": 2, "vendor": "HGST", "model": "HUH721212AL", "firmware": "A1", "size": 100}
+	},
+	"dimms": [
+		{"memory-locator": "A1", "memory-size": 32, "memory-serial-number": "M1"},
+		{"memory-locator": "B1", "memory-size": 32, "memory-serial-number": "M2"}
+	],
+	"interfaces": {
+		"eth0": {"mac": "00:00:00:00:00:01", "product": "X710", "vendor": "Intel"}
+	}
+}`
+
+// testDiffReports reads the test report and a copy with the changes made by
+// edit
+func testDiffReports(t *testing.T, edit func(r *types.DeviceReport)) (types.DeviceReport, types.DeviceReport) {
+	var old, new types.DeviceReport
+	assert.Nil(t, json.Unmarshal([]byte(testDiffReport), &old))
+	assert.Nil(t, json.Unmarshal([]byte(testDiffReport), &new))
+	edit(&new)
+	return old, new
+}
+
+func TestDiffDeviceReports(t *testing.T) {
+	tests := []struct {
+		Name       string
+		SameDevice bool
+		Edit       func(r *types.DeviceReport)
+		Expected   reportDiff
+	}{
+		{
+			Name:       "no changes",
+			SameDevice: true,
+			Edit:       func(r *types.DeviceReport) {},
+		},
+		{
+			Name:       "system",
+			SameDevice: true,
+			Edit: func(r *types.DeviceReport) {
+				r.BiosVersion = "1.1"
+				r.Cpus = r.Cpus[:1]
+			},
+			Expected: reportDiff{
+				{Component: "system", Key: "device", Change: reportChanged, Field: "bios_version", Old: "1.0", New: "1.1"},
+				{Component: "system", Key: "device", Change: reportChanged, Field: "cpus", Old: "2", New: "1"},
+			},
+		},
+		{
+			Name:       "a disk replaced and another updated",
+			SameDevice: true,
+			Edit: func(r *types.DeviceReport) {
+				d := r.Disks["D2"]
+				delete(r.Disks, "D2")
+				r.Disks["D3"] = d
+				d = r.Disks["D1"]
+				d.Firmware = "A2"
+				r.Disks["D1"] = d
+			},
+			Expected: reportDiff{
+				{Component: "disk", Key: "D1", Change: reportChanged, Field: "firmware", Old: "A1", New: "A2"},
+				{Component: "disk", Key: "D2", Change: reportRemoved, Old: "enclosure=0, firmware=A1, model=HUH721212AL, size=100, slot=2, vendor=HGST"},
+				{Component: "disk", Key: "D3", Change: reportAdded, New: "enclosure=0, firmware=A1, model=HUH721212AL, size=100, slot=2, vendor=HGST"},
+			},
+		},
+		{
+			Name:       "a DIMM removed and one replaced",
+			SameDevice: true,
+			Edit: func(r *types.DeviceReport) {
+				r.Dimms = r.Dimms[:1]
+				r.Dimms[0].MemorySerialNumber = "M3"
+			},
+			Expected: reportDiff{
+				{Component: "dimm", Key: "A1", Change: reportChanged, Field: "serial_number", Old: "M1", New: "M3"},
+				{Component: "dimm", Key: "B1", Change: reportRemoved, Old: "serial_number=M2, size=32"},
+			},
+		},
+		{
+			Name:       "an interface renamed",
+			SameDevice: true,
+			Edit: func(r *types.DeviceReport) {
+				r.Interfaces = map[string]types.Interface{"ixgbe0": r.Interfaces["eth0"]}
+			},
+			Expected: reportDiff{
+				{Component: "interface", Key: "00:00:00:00:00:01", Change: reportChanged, Field: "name", Old: "eth0", New: "ixgbe0"},
+			},
+		},
+		{
+			Name: "another device matches disks by location and ignores serials and MACs",
+			Edit: func(r *types.DeviceReport) {
+				r.Disks = map[string]types.Disk{
+					"X1": r.Disks["D1"],
+					"X2": r.Disks["D2"],
+				}
+				r.Dimms[0].MemorySerialNumber = "M9"
+				i := r.Interfaces["eth0"]
+				i.Mac = "00:00:00:00:00:09"
+				r.Interfaces["eth0"] = i
+
+				d := r.Disks["X2"]
+				d.Model = "HUH721010AL"
+				r.Disks["X2"] = d
+			},
+			Expected: reportDiff{
+				{Component: "disk", Key: "enclosure 0 slot 2", Change: reportChanged, Field: "model", Old: "HUH721212AL", New: "HUH721010AL"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			old, new := testDiffReports(t, test.Edit)
+			assert.Equal(t, test.Expected, diffDeviceReports(old, new, test.SameDevice))
+		})
+	}
+}
+
+func TestReadDeviceReport(t *testing.T) {
+	// a report as sent by a reporter and a stored report row
+	for _, input := range []string{
+		testDiffReport,
+		`{"id": "00000000-0000-0000-0000-000000000001", "report": ` + testDiffReport + `}`,
+	} {
+		report, err := readDeviceReport(strings.NewReader(input))
+		assert.Nil(t, err)
+		assert.Equal(t, "SN1", string(report.SerialNumber))
+		assert.Len(t, report.Disks, 2)
+	}
+
+	_, err := readDeviceReport(strings.NewReader(`[]`))
+	assert.Error(t, err)
+}
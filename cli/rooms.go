@@ -1,8 +1,6 @@
 package cli
 
 import (
-	"errors"
-
 	cli "github.com/jawher/mow.cli"
 	"github.com/joyent/kosh/conch"
 	"github.com/joyent/kosh/conch/types"
@@ -40,28 +38,28 @@ func roomsCmd(cmd *cli.Cmd) {
 			// '--alias ""' which will pass the cli lib's requirement
 			// check but is still crap
 			if *aliasOpt == "" {
-				fatalIf(errors.New("--alias is required"))
+				fatalIf(errUsage("--alias is required"))
 			}
 			if *azOpt == "" {
-				fatalIf(errors.New("--az is required"))
+				fatalIf(errUsage("--az is required"))
 			}
 			if *datacenterIDOpt == "" {
-				fatalIf(errors.New("--datacenter-id is required"))
+				fatalIf(errUsage("--datacenter-id is required"))
 			}
 
 			datacenter, e := conch.GetDatacenterByName(*datacenterIDOpt)
 			fatalIf(e)
 
 			if (datacenter == types.Datacenter{}) {
-				fatalIf(errors.New("could not find the datacenter"))
+				fatalIf(errNotFound("could not find the datacenter"))
 			}
 
-			conch.CreateRoom(types.DatacenterRoomCreate{
+			fatalIf(conch.CreateRoom(types.DatacenterRoomCreate{
 				DatacenterID: datacenter.ID,
 				Az:           types.NonEmptyString(*azOpt),
 				Alias:        types.MojoStandardPlaceholder(*aliasOpt),
 				VendorName:   types.MojoRelaxedPlaceholder(*vendorNameOpt),
-			})
+			}))
 		}
 	})
 }
@@ -90,7 +88,7 @@ func roomCmd(cmd *cli.Cmd) {
 		room, e = conch.GetRoomByAlias(*aliasArg)
 		fatalIf(e)
 		if (room == types.DatacenterRoomDetailed{}) {
-			fatalIf(errors.New("could not find the room"))
+			fatalIf(errNotFound("could not find the room"))
 		}
 	}
 
@@ -110,22 +108,22 @@ func roomCmd(cmd *cli.Cmd) {
 			dc, e := conch.GetDatacenterByName(*datacenterIDOpt)
 			fatalIf(e)
 			if (dc == types.Datacenter{}) {
-				fatalIf(errors.New("could not find the datacenter"))
+				fatalIf(errNotFound("could not find the datacenter"))
 			}
 
-			conch.UpdateRoom(room.ID, types.DatacenterRoomUpdate{
+			fatalIf(conch.UpdateRoom(room.ID, types.DatacenterRoomUpdate{
 				DatacenterID: dc.ID,
 				Az:           types.NonEmptyString(*azOpt),
 				Alias:        types.MojoStandardPlaceholder(*aliasOpt),
 				VendorName:   types.MojoRelaxedPlaceholder(*vendorNameOpt),
-			})
+			}))
 			display(conch.GetRoomByID(room.ID))
 		}
 	})
 
 	cmd.Command("delete", "Delete a single room", func(cmd *cli.Cmd) {
 		cmd.Action = func() {
-			conch.DeleteRoom(room.ID)
+			fatalIf(conch.DeleteRoom(room.ID))
 			display(conch.GetAllRooms())
 		}
 	})
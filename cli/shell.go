@@ -0,0 +1,545 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	cli "github.com/jawher/mow.cli"
+	"github.com/joyent/kosh/conch"
+	"github.com/joyent/kosh/conch/types"
+	"golang.org/x/term"
+)
+
+const shellHelp = `Commands are the same as for kosh itself, without the 'kosh', e.g. 'builds ls'.
+
+  use                 Show the build and rack in use
+  use build [NAME]    Run build commands against NAME, e.g. 'racks' for 'build NAME racks'
+  use rack [UUID]     Run rack commands against the rack, e.g. 'layout' for 'rack UUID layout'
+  set                 Show the session settings
+  set output FORMAT   Change the output format for the rest of the session
+  help                Show this help, use 'COMMAND --help' for help on a command
+  exit, quit          Leave the shell (or Ctrl-D)
+
+Leaving out the name in 'use' stops using the build or rack. The build and rack
+are looked up once, run 'use' again to pick up changes made to them.
+`
+
+// shellBuiltins are the commands handled by the shell itself
+var shellBuiltins = []string{"exit", "help", "quit", "set", "use"}
+
+// shellHistorySize is how many lines of history are kept between sessions,
+// the same number the terminal keeps
+const shellHistorySize = 100
+
+// shellAbort is panicked by fatalIf in place of exiting when a command run
+// from kosh shell fails, so the shell can carry on with the next one
+type shellAbort int
+
+// shellSession is the state kept between the command lines of kosh shell
+type shellSession struct {
+	// config is restored before running each command line, so options given
+	// on one line don't leak into the next
+	config Config
+	tree   *completionTree
+
+	build     *types.Build
+	buildName string
+	rack      *types.Rack
+
+	client    *conch.Client
+	clientKey string
+
+	history     []string
+	historyPath string
+}
+
+// conchClient returns the client shared by every command in the session, as
+// long as it's for the same API, credentials and settings
+func (s *shellSession) conchClient(key string, create func() *conch.Client) *conch.Client {
+	if s.client == nil || s.clientKey != key {
+		s.client = create()
+		s.clientKey = key
+	}
+	return s.client
+}
+
+// contextBuild returns the build in use when it's the one named, so build
+// commands don't have to look it up again
+func (s *shellSession) contextBuild(name string) (types.Build, bool) {
+	if s == nil || s.build == nil || name != s.buildName {
+		return types.Build{}, false
+	}
+	return *s.build, true
+}
+
+// contextRack returns the rack in use when it's the one named
+func (s *shellSession) contextRack(name string) (types.Rack, bool) {
+	if s == nil || s.rack == nil || name != s.rack.ID.String() {
+		return types.Rack{}, false
+	}
+	return *s.rack, true
+}
+
+func (s *shellSession) prompt() string {
+	var context []string
+	if s.build != nil {
+		context = append(context, fmt.Sprintf("build %s", s.buildName))
+	}
+	if s.rack != nil {
+		context = append(context, fmt.Sprintf("rack %s", s.rack.Name))
+	}
+	if len(context) == 0 {
+		return "kosh> "
+	}
+	return fmt.Sprintf("kosh [%s]> ", strings.Join(context, ", "))
+}
+
+// guard runs f with the session config, returning the exit code it would have
+// exited with. The context f sets up for its requests is cancelled when it's
+// done, however it finishes.
+func (s *shellSession) guard(f func()) (code int) {
+	config = s.config
+	defer func() {
+		if config.cancel != nil {
+			config.cancel()
+		}
+		if r := recover(); r != nil {
+			abort, ok := r.(shellAbort)
+			if !ok {
+				panic(r)
+			}
+			code = int(abort)
+		}
+	}()
+	f()
+	return ExitOK
+}
+
+// run runs a kosh command line
+func (s *shellSession) run(args []string) int {
+	return s.guard(func() {
+		app := NewApp(s.config)
+		// defining the global options reset them to their defaults, put the
+		// session's settings back before the command line is parsed
+		config = s.config
+		if e := app.Run(append([]string{"kosh"}, args...)); e != nil {
+			// mow.cli has already printed the error and the usage
+			panic(shellAbort(ExitUsage))
+		}
+	})
+}
+
+// expand puts the build or rack in use in front of a command that belongs to
+// it, the rack first as it's the more specific
+func (s *shellSession) expand(words []string) []string {
+	if s.rack != nil {
+		node := s.tree.node([]string{"rack", "UUID"}, []bool{false, true})
+		if _, ok := node.subcommand(words[0]); ok {
+			return append([]string{"rack", s.rack.ID.String()}, words...)
+		}
+	}
+	if s.build != nil {
+		node := s.tree.node([]string{"build", "NAME"}, []bool{false, true})
+		if _, ok := node.subcommand(words[0]); ok {
+			return append([]string{"build", s.buildName}, words...)
+		}
+	}
+	return words
+}
+
+func (s *shellSession) use(args []string) {
+	if len(args) == 0 {
+		if s.build == nil && s.rack == nil {
+			fmt.Println("No build or rack in use")
+		}
+		if s.build != nil {
+			fmt.Printf("build: %s\n", s.buildName)
+		}
+		if s.rack != nil {
+			fmt.Printf("rack: %s (%s)\n", s.rack.Name, s.rack.ID)
+		}
+		return
+	}
+
+	switch {
+	case args[0] == "build" && len(args) == 1:
+		s.build, s.buildName = nil, ""
+	case args[0] == "build" && len(args) == 2:
+		s.guard(func() {
+			config.requireAuth()
+			config.Context, config.cancel = newContext(config.Timeout)
+			build, e := config.ConchClient().GetBuildByName(args[1])
+			fatalIf(e)
+			s.build, s.buildName = &build, args[1]
+		})
+	case args[0] == "rack" && len(args) == 1:
+		s.rack = nil
+	case args[0] == "rack" && len(args) == 2:
+		s.guard(func() {
+			config.requireAuth()
+			config.Context, config.cancel = newContext(config.Timeout)
+			rack, e := config.ConchClient().GetRackByName(args[1])
+			fatalIf(e)
+			if (rack == types.Rack{}) {
+				fatalIf(errNotFound("could not find the rack"))
+			}
+			s.rack = &rack
+		})
+	default:
+		fmt.Fprintln(os.Stderr, "usage: use [build [NAME] | rack [UUID]]")
+	}
+}
+
+func (s *shellSession) set(args []string) {
+	switch {
+	case len(args) == 0:
+		fmt.Printf("output: %s\n", s.config.OutputFormat())
+	case args[0] == "output" && len(args) == 2:
+		if !okOutput(args[1]) {
+			fmt.Fprintf(os.Stderr, "output must be one of: %s\n", strings.Join(outputList, ", "))
+			return
+		}
+		s.config.Output = args[1]
+		s.config.OutputJSON = false
+	default:
+		fmt.Fprintln(os.Stderr, "usage: set [output FORMAT]")
+	}
+}
+
+// execute runs a single line, returning true when the shell should exit
+func (s *shellSession) execute(line string) bool {
+	words, e := splitWords(line)
+	if e != nil {
+		fmt.Fprintln(os.Stderr, e)
+		return false
+	}
+	if len(words) == 0 || strings.HasPrefix(words[0], "#") {
+		return false
+	}
+
+	switch words[0] {
+	case "exit", "quit":
+		return true
+	case "help":
+		fmt.Print(shellHelp)
+	case "use":
+		s.use(words[1:])
+	case "set":
+		s.set(words[1:])
+	default:
+		s.run(s.expand(words))
+	}
+	return false
+}
+
+// complete returns the candidates for the last of the words, the shell's own
+// commands or those of kosh
+func (s *shellSession) complete(words []string) []string {
+	current := words[len(words)-1]
+
+	var candidates []string
+	switch {
+	case len(words) == 1:
+		candidates = append(matching(shellBuiltins, current), s.completeCommand(words)...)
+	case words[0] == "use" && len(words) == 2:
+		candidates = matching([]string{"build", "rack"}, current)
+	case words[0] == "use" && len(words) == 3 && words[1] == "build":
+		candidates = complete(s.tree, []string{"build", current})
+	case words[0] == "set" && len(words) == 2:
+		candidates = matching([]string{"output"}, current)
+	case words[0] == "set" && len(words) == 3 && words[1] == "output":
+		candidates = matching(outputList, current)
+	case isShellBuiltin(words[0]):
+		// nothing more to complete for the shell's own commands
+	default:
+		candidates = s.completeCommand(words)
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+// completeCommand completes a kosh command, including the commands of the
+// build or rack in use
+func (s *shellSession) completeCommand(words []string) []string {
+	candidates := complete(s.tree, words)
+	if s.rack != nil {
+		prefix := []string{"rack", s.rack.ID.String()}
+		candidates = append(candidates, complete(s.tree, append(prefix, words...))...)
+	}
+	if s.build != nil {
+		prefix := []string{"build", s.buildName}
+		candidates = append(candidates, complete(s.tree, append(prefix, words...))...)
+	}
+
+	seen := map[string]bool{}
+	var unique []string
+	for _, c := range candidates {
+		if !seen[c] {
+			seen[c] = true
+			unique = append(unique, c)
+		}
+	}
+	return unique
+}
+
+// autoComplete is the tab completion callback for the terminal, it fills in
+// as much as all the candidates have in common and lists them when that's
+// nothing more
+func (s *shellSession) autoComplete(t *term.Terminal) func(string, int, rune) (string, int, bool) {
+	return func(line string, pos int, key rune) (string, int, bool) {
+		if key != '\t' {
+			return "", 0, false
+		}
+		defer s.tree.save()
+
+		head := line[:pos]
+		words, e := splitWords(head)
+		if e != nil {
+			return "", 0, false
+		}
+		if len(words) == 0 || strings.HasSuffix(head, " ") {
+			words = append(words, "")
+		}
+		current := words[len(words)-1]
+
+		candidates := s.complete(words)
+		if len(candidates) == 0 {
+			return "", 0, false
+		}
+
+		prefix := commonPrefix(candidates)
+		if len(candidates) > 1 && prefix == current {
+			fmt.Fprintf(t, "%s\r\n", strings.Join(candidates, "  "))
+			return "", 0, false
+		}
+
+		completed := head[:len(head)-len(current)] + prefix
+		if len(candidates) == 1 {
+			completed += " "
+		}
+		return completed + line[pos:], len(completed), true
+	}
+}
+
+// loadHistory reads the history saved by earlier sessions. Lines with control
+// characters are left out, they'd be taken as key presses when replayed.
+func (s *shellSession) loadHistory() {
+	b, e := ioutil.ReadFile(s.historyPath)
+	if e != nil {
+		return
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		if line != "" && strings.IndexFunc(line, unicode.IsControl) < 0 {
+			s.history = append(s.history, line)
+		}
+	}
+	if len(s.history) > shellHistorySize {
+		s.history = s.history[len(s.history)-shellHistorySize:]
+	}
+}
+
+// remember adds a line to the history and saves it for later sessions
+func (s *shellSession) remember(line string) {
+	if strings.TrimSpace(line) == "" || strings.IndexFunc(line, unicode.IsControl) >= 0 {
+		return
+	}
+	s.history = append(s.history, line)
+	if len(s.history) > shellHistorySize {
+		s.history = s.history[len(s.history)-shellHistorySize:]
+	}
+	if s.historyPath == "" {
+		return
+	}
+	os.MkdirAll(filepath.Dir(s.historyPath), 0700)
+	ioutil.WriteFile(s.historyPath, []byte(strings.Join(s.history, "\n")+"\n"), 0600)
+}
+
+// shellTerminal is the terminal's connection, which can be swapped out
+type shellTerminal struct {
+	io.Reader
+	io.Writer
+}
+
+// replayHistory fills in the terminal's history. The terminal has no way to
+// set it, so the lines are typed into it with the output thrown away.
+func replayHistory(t *term.Terminal, conn *shellTerminal, history []string) {
+	if len(history) == 0 {
+		return
+	}
+	reader, writer := conn.Reader, conn.Writer
+	conn.Reader = strings.NewReader(strings.Join(history, "\r") + "\r")
+	conn.Writer = ioutil.Discard
+	for range history {
+		t.ReadLine()
+	}
+	conn.Reader, conn.Writer = reader, writer
+}
+
+// loop reads and executes command lines until the input ends or the user
+// exits, with line editing and history when reading from a terminal
+func (s *shellSession) loop() error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if s.execute(scanner.Text()) {
+				return nil
+			}
+		}
+		return scanner.Err()
+	}
+
+	conn := &shellTerminal{os.Stdin, os.Stdout}
+	t := term.NewTerminal(conn, s.prompt())
+	s.loadHistory()
+	replayHistory(t, conn, s.history)
+	t.AutoCompleteCallback = s.autoComplete(t)
+
+	for {
+		state, e := term.MakeRaw(fd)
+		if e != nil {
+			return e
+		}
+		if width, height, e := term.GetSize(fd); e == nil && width > 0 {
+			t.SetSize(width, height)
+		}
+		line, e := t.ReadLine()
+		term.Restore(fd, state)
+
+		if e == io.EOF {
+			fmt.Println()
+			return nil
+		}
+		if e != nil {
+			return e
+		}
+		s.remember(line)
+		if s.execute(line) {
+			return nil
+		}
+		t.SetPrompt(s.prompt())
+	}
+}
+
+func shellCmd(cmd *cli.Cmd) {
+	cmd.LongDesc = `Start an interactive shell that runs kosh commands, keeping the connection to
+the API, the build and rack in use and the output format between commands.
+The last 100 lines typed are kept for the next session's history. Type 'help'
+in the shell for the commands it adds.`
+
+	cmd.Action = func() {
+		if config.shell != nil {
+			fatalIf(errUsage("already in kosh shell"))
+		}
+
+		// the shell's own context isn't used, each command gets one that's
+		// cancelled when the command is done
+		if config.cancel != nil {
+			config.cancel()
+		}
+
+		s := &shellSession{
+			tree:        loadCompletionTree(),
+			historyPath: filepath.Join(completionCacheDir(), "shell-history"),
+		}
+		s.config = config
+		s.config.shell = s
+		s.config.Context, s.config.cancel = nil, nil
+
+		// Ctrl-C interrupts the command being run, not the shell
+		signal.Notify(make(chan os.Signal, 1), os.Interrupt)
+
+		e := s.loop()
+		s.tree.save()
+		fatalIf(e)
+	}
+}
+
+// splitWords splits a command line into words the way a POSIX shell does,
+// with quotes and backslash escapes but no expansions
+func splitWords(line string) ([]string, error) {
+	var (
+		words   []string
+		word    strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == '\'':
+			word.WriteRune(r)
+		case r == '\\':
+			escaped, inWord = true, true
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if escaped {
+		return nil, errors.New("trailing backslash")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+func isShellBuiltin(word string) bool {
+	for _, b := range shellBuiltins {
+		if word == b {
+			return true
+		}
+	}
+	return false
+}
+
+// matching returns the candidates that start with the prefix
+func matching(candidates []string, prefix string) []string {
+	var matches []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) {
+			matches = append(matches, c)
+		}
+	}
+	return matches
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
@@ -0,0 +1,181 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/joyent/kosh/conch/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		Line     string
+		Expected []string
+		Err      bool
+	}{
+		{"", nil, false},
+		{"   ", nil, false},
+		{"builds ls", []string{"builds", "ls"}, false},
+		{"  rack  x\tlayout ", []string{"rack", "x", "layout"}, false},
+		{`device set "a b" 'c "d"'`, []string{"device", "set", "a b", `c "d"`}, false},
+		{`a\ b "c\"d" 'e\f'`, []string{"a b", `c"d`, `e\f`}, false},
+		{`empty "" ''`, []string{"empty", "", ""}, false},
+		{`x"y"z`, []string{"xyz"}, false},
+		{`"open`, nil, true},
+		{`trailing \`, nil, true},
+	}
+
+	for _, test := range tests {
+		words, err := splitWords(test.Line)
+		if test.Err {
+			assert.Error(t, err, test.Line)
+			continue
+		}
+		assert.Nil(t, err, test.Line)
+		assert.Equal(t, test.Expected, words, test.Line)
+	}
+}
+
+func TestCommonPrefix(t *testing.T) {
+	assert.Equal(t, "build", commonPrefix([]string{"build"}))
+	assert.Equal(t, "build", commonPrefix([]string{"builds", "build"}))
+	assert.Equal(t, "json", commonPrefix([]string{"json", "json-pretty", "jsonl"}))
+	assert.Equal(t, "", commonPrefix([]string{"rack", "build"}))
+}
+
+// testShell is a session using a build and a rack, with a completion tree
+// that never runs kosh
+func testShell() *shellSession {
+	tree := testCompletionTree()
+	root := tree.Nodes[""]
+	root.Commands = append(root.Commands, []string{"rack"})
+	tree.Nodes[""] = root
+	tree.Nodes["build NAME"] = tree.Nodes["build"]
+	tree.Nodes["rack"] = completionNode{Args: []string{"UUID"}, Commands: [][]string{{"layout"}, {"assignments"}}}
+	tree.Nodes["rack UUID"] = tree.Nodes["rack"]
+
+	rack := types.Rack{
+		ID:   types.UUID{UUID: uuid.Must(uuid.FromString("6dd0f1a4-9d8c-4f3e-a5f2-7d1a8a9f0c11"))},
+		Name: "r1",
+	}
+	return &shellSession{
+		tree:      tree,
+		build:     &types.Build{Name: "b1"},
+		buildName: "b1",
+		rack:      &rack,
+	}
+}
+
+func TestShellExpand(t *testing.T) {
+	rackID := "6dd0f1a4-9d8c-4f3e-a5f2-7d1a8a9f0c11"
+	tests := []struct {
+		Words    []string
+		Expected []string
+	}{
+		{[]string{"layout"}, []string{"rack", rackID, "layout"}},
+		{[]string{"orgs", "ls"}, []string{"build", "b1", "orgs", "ls"}},
+		{[]string{"builds", "ls"}, []string{"builds", "ls"}},
+		{[]string{"rack", "other", "layout"}, []string{"rack", "other", "layout"}},
+	}
+
+	s := testShell()
+	for _, test := range tests {
+		assert.Equal(t, test.Expected, s.expand(test.Words), fmt.Sprint(test.Words))
+	}
+
+	s.rack = nil
+	assert.Equal(t, []string{"layout"}, s.expand([]string{"layout"}))
+}
+
+func TestShellComplete(t *testing.T) {
+	tests := []struct {
+		Words    []string
+		Expected []string
+	}{
+		{[]string{""}, []string{"assignments", "build", "builds", "completion", "devices", "exit", "get", "help", "layout", "organizations", "quit", "rack", "set", "use"}},
+		{[]string{"u"}, []string{"use"}},
+		{[]string{"use", ""}, []string{"build", "rack"}},
+		{[]string{"set", ""}, []string{"output"}},
+		{[]string{"set", "output", "json-"}, []string{"json-pretty"}},
+		{[]string{"exit", ""}, nil},
+		{[]string{"la"}, []string{"layout"}},
+		{[]string{"org"}, []string{"organizations"}},
+		{[]string{"ds", ""}, []string{"get"}},
+		{[]string{"completion", "f"}, []string{"fish"}},
+	}
+
+	s := testShell()
+	for _, test := range tests {
+		assert.Equal(t, test.Expected, s.complete(test.Words), fmt.Sprint(test.Words))
+	}
+}
+
+func TestShellHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kosh-shell")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	s := &shellSession{historyPath: filepath.Join(dir, "kosh", "shell-history")}
+	for i := 0; i < shellHistorySize+5; i++ {
+		s.remember(fmt.Sprintf("builds ls %d", i))
+	}
+	s.remember("   ")
+	s.remember("bad\x1b[Aline")
+
+	info, err := os.Stat(s.historyPath)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	loaded := &shellSession{historyPath: s.historyPath}
+	loaded.loadHistory()
+	assert.Len(t, loaded.history, shellHistorySize)
+	assert.Equal(t, "builds ls 5", loaded.history[0])
+	assert.Equal(t, fmt.Sprintf("builds ls %d", shellHistorySize+4), loaded.history[shellHistorySize-1])
+}
+
+func TestShellClient(t *testing.T) {
+	s := &shellSession{}
+	c := NewConfig("test", "test")
+	c.ConchURL = "http://localhost"
+	c.ConchToken = "token"
+	c.shell = s
+
+	c.ConchClient()
+	first := s.client
+	c.ConchClient()
+	assert.True(t, first == s.client)
+
+	// -v and -d given on one command line need a client that logs
+	c.LevelDebug = true
+	c.ConchClient()
+	assert.False(t, first == s.client)
+}
+
+func TestShellUsageHidesToken(t *testing.T) {
+	// mow.cli writes the usage to the stderr it saw when it was loaded, so
+	// the shell runs in a copy of the test binary and its stderr is read
+	if os.Getenv("KOSH_TEST_SHELL_USAGE") == "1" {
+		c := NewConfig("test", "test")
+		c.ConchToken = "sekrit-token"
+		c.TokenCommand = "vault read -token=sekrit-command"
+		s := &shellSession{}
+		c.shell = s
+		s.config = c
+		os.Exit(s.run([]string{"--no-such-option"}))
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestShellUsageHidesToken$")
+	cmd.Env = append(os.Environ(), "KOSH_TEST_SHELL_USAGE=1")
+	out, err := cmd.CombinedOutput()
+	if exit, ok := err.(*exec.ExitError); assert.True(t, ok, "%v", err) {
+		assert.Equal(t, ExitUsage, exit.ExitCode())
+	}
+	assert.Contains(t, string(out), "--token")
+	assert.NotContains(t, string(out), "sekrit")
+}
@@ -46,6 +46,9 @@ func tokensCmd(cmd *cli.Cmd) {
 		user := cmd.StringOpt("user u", "", "User name to use for authentication")
 		pass := cmd.StringOpt("pass p", "", "Password to use for authentication")
 		cmd.Action = func() {
+			if config.DryRun && *user != "" {
+				fatalIf(errUsage("--user can't be used with --dry-run, logging in would be skipped"))
+			}
 			if *user != "" && *pass != "" {
 				loginToken, e := conch.Login(*user, *pass)
 				if e != nil {
@@ -54,7 +57,13 @@ func tokensCmd(cmd *cli.Cmd) {
 				config.Debug(fmt.Sprintf("%+v", loginToken))
 				conch = conch.Authorization("Bearer " + loginToken.JwtToken)
 			}
-			display(conch.CreateCurrentUserToken(types.NewUserTokenRequest{Name: *name}))
+			token, e := conch.CreateCurrentUserToken(types.NewUserTokenRequest{Name: *name})
+			if config.DryRun {
+				// the request wasn't sent, so there's no token to show
+				fatalIf(e)
+				return
+			}
+			display(token, e)
 		}
 	})
 }
@@ -91,7 +100,7 @@ func tokenCmd(cmd *cli.Cmd) {
 
 	cmd.Command("delete rm", "display the user token information", func(cmd *cli.Cmd) {
 		cmd.Action = func() {
-			conch.DeleteCurrentUserToken(token.Name)
+			fatalIf(conch.DeleteCurrentUserToken(token.Name))
 			display(conch.GetCurrentUserTokens())
 		}
 	})
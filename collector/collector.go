@@ -0,0 +1,428 @@
+/*
+Package collector builds a device report on a Linux host from what the kernel
+exposes under /sys and /proc, for hosts that can't run the full reporter, such
+as rescue images.
+
+Everything is read relative to a root directory, "/" on a live host, so a
+copy or a fake of those trees can be collected from instead:
+
+	sys/class/dmi/id           serial number, product name, SKU, BIOS version, UUID
+	sys/firmware/dmi/tables    DIMMs, from the SMBIOS memory device entries
+	sys/class/net              network interfaces backed by a device
+	sys/block                  disks, leaving out loop, RAM and other virtual devices
+	sys/class/hwmon            CPU, inlet and exhaust temperatures
+	proc/cpuinfo               CPUs, one per physical package
+	proc/meminfo               total memory, when the SMBIOS table can't be read
+	proc/sys/kernel/hostname   hostname
+	proc/uptime                when the host booted
+
+Disk sizes are in MB and DIMM sizes in GB, as reporters send them. SMBIOS
+tables are usually only readable by root, without them memory is reported as
+a single entry with the locator "MemTotal".
+*/
+package collector
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/joyent/kosh/conch/types"
+)
+
+// Collector reads the state of a host from the files under Root
+type Collector struct {
+	Root string
+
+	// Now is the current time, used to work out when the host booted
+	Now func() time.Time
+}
+
+// New returns a Collector reading from the given root directory
+func New(root string) *Collector {
+	if root == "" {
+		root = "/"
+	}
+	return &Collector{Root: root, Now: time.Now}
+}
+
+// Collect builds a device report for the host. Only the DMI serial number is
+// required, anything else that can't be read is left out of the report.
+func (c *Collector) Collect() (types.DeviceReport, error) {
+	report := types.DeviceReport{
+		DeviceType:   "server",
+		SerialNumber: types.DeviceSerialNumber(c.read("sys/class/dmi/id/product_serial")),
+		ProductName:  c.read("sys/class/dmi/id/product_name"),
+		Sku:          c.read("sys/class/dmi/id/product_sku"),
+		BiosVersion:  c.read("sys/class/dmi/id/bios_version"),
+	}
+	if report.SerialNumber == "" {
+		return report, fmt.Errorf("could not read the serial number from %s", c.path("sys/class/dmi/id/product_serial"))
+	}
+	if id, e := uuid.FromString(c.read("sys/class/dmi/id/product_uuid")); e == nil {
+		report.SystemUUID = types.UUID{UUID: id}
+	}
+	if hostname := c.read("proc/sys/kernel/hostname"); hostname != "" {
+		report.Os = &types.Os{Hostname: hostname}
+	}
+	report.UptimeSince = c.uptimeSince()
+
+	report.Cpus = c.cpus()
+	report.Dimms = c.dimms()
+	report.Disks = c.disks()
+	report.Interfaces = c.interfaces()
+	report.Temp = c.temperatures()
+
+	return report, nil
+}
+
+// path is where a file is under the root
+func (c *Collector) path(name string) string {
+	return filepath.Join(c.Root, filepath.FromSlash(name))
+}
+
+// read returns the trimmed contents of a file, or "" if it can't be read
+func (c *Collector) read(name string) string {
+	b, e := ioutil.ReadFile(c.path(name))
+	if e != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// readInt returns a file holding a number, or false if it doesn't
+func (c *Collector) readInt(name string) (int, bool) {
+	n, e := strconv.Atoi(c.read(name))
+	return n, e == nil
+}
+
+// list returns the names of the entries of a directory, sorted
+func (c *Collector) list(name string) []string {
+	entries, e := ioutil.ReadDir(c.path(name))
+	if e != nil {
+		return nil
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *Collector) exists(name string) bool {
+	_, e := os.Stat(c.path(name))
+	return e == nil
+}
+
+// uptimeSince is when the host booted, from the seconds since in proc/uptime
+func (c *Collector) uptimeSince() string {
+	fields := strings.Fields(c.read("proc/uptime"))
+	if len(fields) == 0 {
+		return ""
+	}
+	seconds, e := strconv.ParseFloat(fields[0], 64)
+	if e != nil {
+		return ""
+	}
+	booted := c.Now().Add(-time.Duration(seconds * float64(time.Second)))
+	return booted.UTC().Truncate(time.Second).Format(time.RFC3339)
+}
+
+// cpus lists a CPU for each physical package in proc/cpuinfo
+func (c *Collector) cpus() []types.CpusItem {
+	packages := map[string]types.CpusItem{}
+	var ids []string
+
+	for _, block := range strings.Split(c.read("proc/cpuinfo"), "\n\n") {
+		fields := map[string]string{}
+		for _, line := range strings.Split(block, "\n") {
+			parts := strings.SplitN(line, ":", 2)
+			if len(parts) == 2 {
+				fields[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+			}
+		}
+		if _, ok := fields["processor"]; !ok {
+			continue
+		}
+
+		id := fields["physical id"]
+		if id == "" {
+			id = "0"
+		}
+		cpu, ok := packages[id]
+		if !ok {
+			cores, _ := strconv.Atoi(fields["cpu cores"])
+			cpu = types.CpusItem{
+				"id":      id,
+				"vendor":  fields["vendor_id"],
+				"model":   fields["model name"],
+				"cores":   cores,
+				"threads": 0,
+			}
+			packages[id] = cpu
+			ids = append(ids, id)
+		}
+		cpu["threads"] = cpu["threads"].(int) + 1
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		a, _ := strconv.Atoi(ids[i])
+		b, _ := strconv.Atoi(ids[j])
+		return a < b
+	})
+	cpus := make([]types.CpusItem, 0, len(ids))
+	for _, id := range ids {
+		cpus = append(cpus, packages[id])
+	}
+	return cpus
+}
+
+// dimms lists the populated memory devices in the SMBIOS table, falling back
+// to the total in proc/meminfo
+func (c *Collector) dimms() []types.Dimm {
+	table, e := ioutil.ReadFile(c.path("sys/firmware/dmi/tables/DMI"))
+	if e == nil {
+		if dimms := smbiosDimms(table); len(dimms) > 0 {
+			return dimms
+		}
+	}
+
+	for _, line := range strings.Split(c.read("proc/meminfo"), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "MemTotal:" {
+			continue
+		}
+		kb, e := strconv.Atoi(fields[1])
+		if e != nil {
+			return nil
+		}
+		return []types.Dimm{{
+			MemoryLocator: "MemTotal",
+			MemorySize:    (kb + 512*1024) / (1024 * 1024),
+		}}
+	}
+	return nil
+}
+
+// smbiosDimms reads the memory device (type 17) entries of an SMBIOS table,
+// leaving out empty slots
+func smbiosDimms(table []byte) []types.Dimm {
+	var dimms []types.Dimm
+	for len(table) >= 4 {
+		kind, length := table[0], int(table[1])
+		if length < 4 || length > len(table) {
+			break
+		}
+		formatted := table[:length]
+
+		// the strings of the entry follow the formatted part, and end with
+		// an empty string
+		end := length
+		for end+1 < len(table) && !(table[end] == 0 && table[end+1] == 0) {
+			end++
+		}
+		strs := strings.Split(string(table[length:end]), "\x00")
+		str := func(offset int) string {
+			if offset >= len(formatted) {
+				return ""
+			}
+			n := int(formatted[offset])
+			if n == 0 || n > len(strs) {
+				return ""
+			}
+			return strings.TrimSpace(strs[n-1])
+		}
+
+		if kind == 127 {
+			break
+		}
+		if kind == 17 && length >= 0x1B {
+			mb := 0
+			switch size := binary.LittleEndian.Uint16(formatted[0x0C:]); {
+			case size == 0 || size == 0xFFFF:
+			case size == 0x7FFF && length >= 0x20:
+				mb = int(binary.LittleEndian.Uint32(formatted[0x1C:]))
+			case size&0x8000 != 0:
+				mb = int(size&0x7FFF) / 1024
+			default:
+				mb = int(size)
+			}
+			if mb > 0 {
+				dimm := types.Dimm{
+					MemoryLocator: str(0x10),
+					MemorySize:    mb / 1024,
+				}
+				if serial := str(0x18); serial != "" {
+					dimm.MemorySerialNumber = serial
+				}
+				dimms = append(dimms, dimm)
+			}
+		}
+
+		if end+2 > len(table) {
+			break
+		}
+		table = table[end+2:]
+	}
+	return dimms
+}
+
+// virtualDisks are the prefixes of block devices that aren't disks
+var virtualDisks = []string{"loop", "ram", "zram", "dm-", "md", "sr", "nbd", "fd"}
+
+// disks lists the disks in sys/block by serial number, or by name when the
+// serial number can't be read
+func (c *Collector) disks() map[string]types.Disk {
+	disks := map[string]types.Disk{}
+
+	for _, name := range c.list("sys/block") {
+		virtual := false
+		for _, prefix := range virtualDisks {
+			if strings.HasPrefix(name, prefix) {
+				virtual = true
+				break
+			}
+		}
+		dir := "sys/block/" + name
+		if virtual || !c.exists(dir+"/device") {
+			continue
+		}
+
+		disk := types.Disk{
+			Vendor:   c.read(dir + "/device/vendor"),
+			Model:    c.read(dir + "/device/model"),
+			Firmware: c.read(dir + "/device/firmware_rev"),
+		}
+		if disk.Firmware == "" {
+			disk.Firmware = c.read(dir + "/device/rev")
+		}
+		if sectors, ok := c.readInt(dir + "/size"); ok {
+			disk.Size = sectors * 512 / 1000 / 1000
+		}
+		if size, ok := c.readInt(dir + "/queue/logical_block_size"); ok {
+			disk.BlockSz = size
+		}
+
+		// where the device sits in the device tree says what it's plugged in to
+		target, e := filepath.EvalSymlinks(c.path(dir))
+		if e != nil {
+			target = c.path(dir)
+		}
+		media := "HDD"
+		if rotational, ok := c.readInt(dir + "/queue/rotational"); ok && rotational == 0 {
+			media = "SSD"
+		}
+		switch {
+		case strings.HasPrefix(name, "nvme"):
+			disk.Transport = "nvme"
+			disk.DriveType = "NVME_SSD"
+		case strings.Contains(target, "/usb"):
+			disk.Transport = "usb"
+			disk.DriveType = "USB_" + media
+		case strings.Contains(target, "/ata"):
+			disk.Transport = "sata"
+			disk.DriveType = "SATA_" + media
+		default:
+			disk.Transport = "sas"
+			disk.DriveType = "SAS_" + media
+		}
+
+		serial := c.read(dir + "/device/serial")
+		if serial == "" {
+			serial = vpdSerial(c.read(dir + "/device/vpd_pg80"))
+		}
+		if serial == "" {
+			serial = name
+		}
+		disks[serial] = disk
+	}
+	return disks
+}
+
+// vpdSerial is the serial number in a SCSI unit serial number VPD page
+func vpdSerial(page string) string {
+	if len(page) < 4 {
+		return ""
+	}
+	return strings.TrimSpace(strings.Trim(page[4:], "\x00"))
+}
+
+// interfaces lists the network interfaces backed by a device, leaving out
+// loopback, bridges, bonds and other virtual interfaces
+func (c *Collector) interfaces() map[string]types.Interface {
+	interfaces := map[string]types.Interface{}
+
+	for _, name := range c.list("sys/class/net") {
+		dir := "sys/class/net/" + name
+		if !c.exists(dir + "/device") {
+			continue
+		}
+
+		i := types.Interface{
+			Mac:     types.Macaddr(c.read(dir + "/address")),
+			Vendor:  c.read(dir + "/device/vendor"),
+			Product: c.read(dir + "/device/device"),
+		}
+		if mtu, ok := c.readInt(dir + "/mtu"); ok {
+			i.Mtu = mtu
+		}
+		if state := c.read(dir + "/operstate"); state != "" {
+			i.State = state
+		}
+		interfaces[name] = i
+	}
+	return interfaces
+}
+
+// temperatures reads the CPU package temperatures, and the inlet and exhaust
+// temperatures where a sensor is labelled as such, in degrees C
+func (c *Collector) temperatures() *types.Temp {
+	var (
+		temp  types.Temp
+		found bool
+	)
+
+	for _, hwmon := range c.list("sys/class/hwmon") {
+		dir := "sys/class/hwmon/" + hwmon
+		driver := c.read(dir + "/name")
+
+		for _, file := range c.list(dir) {
+			if !strings.HasPrefix(file, "temp") || !strings.HasSuffix(file, "_input") {
+				continue
+			}
+			millis, ok := c.readInt(dir + "/" + file)
+			if !ok {
+				continue
+			}
+			degrees := (millis + 500) / 1000
+			label := strings.ToLower(c.read(dir + "/" + strings.TrimSuffix(file, "_input") + "_label"))
+
+			switch {
+			case label == "package id 0", driver == "k10temp" && label == "tctl":
+				temp.CPU0 = degrees
+			case label == "package id 1":
+				temp.CPU1 = degrees
+			case strings.Contains(label, "inlet"):
+				temp.Inlet = degrees
+			case strings.Contains(label, "exhaust"):
+				temp.Exhaust = degrees
+			default:
+				continue
+			}
+			found = true
+		}
+	}
+
+	if !found {
+		return nil
+	}
+	return &temp
+}
@@ -0,0 +1,177 @@
+package collector_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/joyent/kosh/collector"
+	"github.com/joyent/kosh/conch/types"
+	"github.com/stretchr/testify/assert"
+)
+
+const cpuinfo = `processor	: 0
+vendor_id	: GenuineIntel
+model name	: Intel(R) Xeon(R) Gold 6130
+physical id	: 0
+cpu cores	: 2
+
+processor	: 1
+vendor_id	: GenuineIntel
+model name	: Intel(R) Xeon(R) Gold 6130
+physical id	: 0
+cpu cores	: 2
+
+processor	: 2
+vendor_id	: GenuineIntel
+model name	: Intel(R) Xeon(R) Gold 6130
+physical id	: 1
+cpu cores	: 2
+`
+
+// memoryDevice is an SMBIOS type 17 entry of the given size in MB, sizes of
+// 32GB and over go in the extended size
+func memoryDevice(mb uint32, locator, serial string) []byte {
+	entry := make([]byte, 0x22)
+	entry[0], entry[1] = 17, byte(len(entry))
+	if mb < 0x7FFF {
+		binary.LittleEndian.PutUint16(entry[0x0C:], uint16(mb))
+	} else {
+		binary.LittleEndian.PutUint16(entry[0x0C:], 0x7FFF)
+		binary.LittleEndian.PutUint32(entry[0x1C:], mb)
+	}
+	entry[0x10], entry[0x18] = 1, 2
+
+	var b bytes.Buffer
+	b.Write(entry)
+	b.WriteString(locator + "\x00" + serial + "\x00\x00")
+	return b.Bytes()
+}
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	for name, contents := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.Nil(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	}
+}
+
+func TestCollect(t *testing.T) {
+	root, err := ioutil.TempDir("", "kosh-collector")
+	assert.Nil(t, err)
+	defer os.RemoveAll(root)
+
+	var dmi bytes.Buffer
+	dmi.Write(memoryDevice(32768, "P1-DIMMA1", "M1"))
+	dmi.Write(memoryDevice(0, "P1-DIMMA2", "NO DIMM"))
+	dmi.Write(memoryDevice(16384, "P1-DIMMB1", "M2"))
+	dmi.Write([]byte{127, 4, 0, 0, 0, 0})
+
+	writeTree(t, root, map[string]string{
+		"sys/class/dmi/id/product_serial": "SN1\n",
+		"sys/class/dmi/id/product_name":   "Joyent-Compute-Platform\n",
+		"sys/class/dmi/id/product_sku":    "600-0001\n",
+		"sys/class/dmi/id/bios_version":   "2.8.1\n",
+		"sys/class/dmi/id/product_uuid":   "6dd0f1a4-9d8c-4f3e-a5f2-7d1a8a9f0c11\n",
+		"sys/firmware/dmi/tables/DMI":     dmi.String(),
+
+		"proc/cpuinfo":             cpuinfo,
+		"proc/meminfo":             "MemTotal:       65830000 kB\n",
+		"proc/sys/kernel/hostname": "host1\n",
+		"proc/uptime":              "3600.50 7000.00\n",
+
+		"sys/class/net/lo/address":              "00:00:00:00:00:00\n",
+		"sys/class/net/eth0/address":            "aa:bb:cc:dd:ee:01\n",
+		"sys/class/net/eth0/mtu":                "9000\n",
+		"sys/class/net/eth0/operstate":          "up\n",
+		"sys/class/net/eth0/device/vendor":      "0x8086\n",
+		"sys/class/net/eth0/device/device":      "0x1572\n",
+		"sys/class/net/bond0/address":           "aa:bb:cc:dd:ee:01\n",
+		"sys/block/loop0/size":                  "1024\n",
+		"sys/block/sda/size":                    "1953525168\n",
+		"sys/block/sda/queue/rotational":        "1\n",
+		"sys/block/sda/device/vendor":           "HGST\n",
+		"sys/block/sda/device/model":            "HUS726T4TAL\n",
+		"sys/block/sda/device/rev":              "A907\n",
+		"sys/block/sda/device/vpd_pg80":         "\x00\x80\x00\x08SASDISK1",
+		"sys/block/nvme0n1/size":                "1875385008\n",
+		"sys/block/nvme0n1/queue/rotational":    "0\n",
+		"sys/block/nvme0n1/device/model":        "INTEL SSDPE2KX010T8\n",
+		"sys/block/nvme0n1/device/serial":       "NVME1\n",
+		"sys/block/nvme0n1/device/firmware_rev": "VDV10131\n",
+
+		"sys/class/hwmon/hwmon0/name":        "coretemp\n",
+		"sys/class/hwmon/hwmon0/temp1_label": "Package id 0\n",
+		"sys/class/hwmon/hwmon0/temp1_input": "45000\n",
+		"sys/class/hwmon/hwmon0/temp2_label": "Core 0\n",
+		"sys/class/hwmon/hwmon0/temp2_input": "43000\n",
+		"sys/class/hwmon/hwmon1/name":        "coretemp\n",
+		"sys/class/hwmon/hwmon1/temp1_label": "Package id 1\n",
+		"sys/class/hwmon/hwmon1/temp1_input": "47600\n",
+	})
+
+	c := collector.New(root)
+	c.Now = func() time.Time { return time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC) }
+
+	report, err := c.Collect()
+	assert.Nil(t, err)
+
+	assert.Equal(t, types.DeviceSerialNumber("SN1"), report.SerialNumber)
+	assert.Equal(t, "Joyent-Compute-Platform", report.ProductName)
+	assert.Equal(t, "600-0001", report.Sku)
+	assert.Equal(t, "2.8.1", report.BiosVersion)
+	assert.Equal(t, "6dd0f1a4-9d8c-4f3e-a5f2-7d1a8a9f0c11", report.SystemUUID.String())
+	assert.Equal(t, &types.Os{Hostname: "host1"}, report.Os)
+	assert.Equal(t, "2020-06-01T10:59:59Z", report.UptimeSince)
+
+	assert.Equal(t, []types.CpusItem{
+		{"id": "0", "vendor": "GenuineIntel", "model": "Intel(R) Xeon(R) Gold 6130", "cores": 2, "threads": 2},
+		{"id": "1", "vendor": "GenuineIntel", "model": "Intel(R) Xeon(R) Gold 6130", "cores": 2, "threads": 1},
+	}, report.Cpus)
+
+	assert.Equal(t, []types.Dimm{
+		{MemoryLocator: "P1-DIMMA1", MemorySize: 32, MemorySerialNumber: "M1"},
+		{MemoryLocator: "P1-DIMMB1", MemorySize: 16, MemorySerialNumber: "M2"},
+	}, report.Dimms)
+
+	assert.Equal(t, map[string]types.Interface{
+		"eth0": {Mac: "aa:bb:cc:dd:ee:01", Mtu: 9000, State: "up", Vendor: "0x8086", Product: "0x1572"},
+	}, report.Interfaces)
+
+	assert.Equal(t, map[string]types.Disk{
+		"SASDISK1": {Vendor: "HGST", Model: "HUS726T4TAL", Firmware: "A907", Size: 1000204, Transport: "sas", DriveType: "SAS_HDD"},
+		"NVME1":    {Model: "INTEL SSDPE2KX010T8", Firmware: "VDV10131", Size: 960197, Transport: "nvme", DriveType: "NVME_SSD"},
+	}, report.Disks)
+
+	assert.Equal(t, &types.Temp{CPU0: 45, CPU1: 48}, report.Temp)
+}
+
+func TestCollectMemTotal(t *testing.T) {
+	root, err := ioutil.TempDir("", "kosh-collector")
+	assert.Nil(t, err)
+	defer os.RemoveAll(root)
+
+	writeTree(t, root, map[string]string{
+		"sys/class/dmi/id/product_serial": "SN2\n",
+		"proc/meminfo":                    "MemTotal:       65830000 kB\nMemFree:        1000 kB\n",
+	})
+
+	report, err := collector.New(root).Collect()
+	assert.Nil(t, err)
+	assert.Equal(t, []types.Dimm{{MemoryLocator: "MemTotal", MemorySize: 63}}, report.Dimms)
+	assert.Nil(t, report.Temp)
+	assert.Empty(t, report.Disks)
+}
+
+func TestCollectNoSerial(t *testing.T) {
+	root, err := ioutil.TempDir("", "kosh-collector")
+	assert.Nil(t, err)
+	defer os.RemoveAll(root)
+
+	_, err = collector.New(root).Collect()
+	assert.NotNil(t, err)
+}
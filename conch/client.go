@@ -1,6 +1,7 @@
 package conch
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"net/http"
@@ -77,14 +78,41 @@ func Logger(logger logger.Interface) Option {
 type Client struct {
 	Sling  *sling.Sling
 	Logger logger.Interface
+
+	ctx    context.Context
+	retry  RetryPolicy
+	dryRun *DryRunLog
 }
 
 // New performs a shallow clone of the current client and returns the
 // new instance
 func (c *Client) New() *Client {
-	s := c.Sling.New()
-	l := c.Logger
-	return &Client{s, l}
+	return &Client{
+		Sling:  c.Sling.New(),
+		Logger: c.Logger,
+		ctx:    c.ctx,
+		retry:  c.retry,
+		dryRun: c.dryRun,
+	}
+}
+
+// WithContext returns a copy of the client whose requests are bound to the
+// given context. Every request built from the returned client, including
+// those made by the resource methods (GetRackLayout, etc), will be cancelled
+// when the context is cancelled or its deadline passes.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	c = c.New()
+	c.ctx = ctx
+	return c
+}
+
+// Context returns the context requests from this client are bound to. If no
+// context has been set it returns context.Background()
+func (c *Client) Context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
 }
 
 // UserAgent sets the client's User-Agent header in the request
@@ -368,50 +396,78 @@ func (c *Client) Delete(data ...interface{}) *Client {
 }
 
 // Send sends a HTTP request to the API server  without expecting a return data
-// structure. It returns the *http.Response and/or error from the request.
+// structure. It returns the *http.Response and/or error from the request. If
+// the server responds with an error status the error will be an *APIError.
 func (c *Client) Send() (*http.Response, error) {
 	c.Logger.Debug("Send")
-	req, err := c.Sling.Request()
-	c.Logger.Info(fmt.Sprintf("URL: %v", req.URL))
-	c.Logger.Debug(req, err)
-
-	res, err := c.Sling.Do(req, nil, nil)
-	c.Logger.Debug(res, err)
-	if err != nil {
-		return res, err
-	}
-
-	if res.StatusCode >= 400 {
-		return res, fmt.Errorf("http error: %v", res.Status)
-	}
-
-	return res, err
+	return c.do(nil)
 }
 
 // Receive sends a HTTP request to the API server and decodes the results into
 // the provided structure structure. It returns the *http.Response and/or error
-// from the request.
+// from the request. If the server responds with an error status the error will
+// be an *APIError.
 func (c *Client) Receive(data interface{}) (*http.Response, error) {
 	if c.Logger != nil {
 		c.Logger.Debug("Receive")
 	}
-	req, err := c.Sling.Request()
+	res, err := c.do(data)
 	if c.Logger != nil {
-		c.Logger.Info(fmt.Sprintf("URL: %v", req.URL))
-		c.Logger.Debug(req, err)
-	}
-
-	res, err := c.Sling.ReceiveSuccess(data)
-	if c.Logger != nil {
-		c.Logger.Debug(res, err)
 		c.Logger.Debug(data)
 	}
-	if err != nil {
-		return res, err
+	return res, err
+}
+
+// do builds the request, sends it, and decodes a successful response into
+// data (if data is not nil). Error responses are decoded into an *APIError.
+// Requests that fail for transient reasons are retried according to the
+// client's RetryPolicy. In dry run mode requests that change data are recorded
+// rather than sent.
+func (c *Client) do(data interface{}) (*http.Response, error) {
+	log := c.Logger
+	if log == nil {
+		log = logger.NullLogger{}
 	}
 
-	if res.StatusCode >= 400 {
-		return res, fmt.Errorf("http error: %v", res.Status)
+	for attempt := 1; ; attempt++ {
+		// sling builds a fresh request (and body) every time it's asked
+		req, err := c.Sling.Request()
+		if err != nil {
+			return nil, err
+		}
+		req = req.WithContext(c.Context())
+		log.Info(fmt.Sprintf("URL: %v", req.URL))
+		log.Debug(req)
+
+		if c.dryRun != nil && changesData(req.Method) {
+			return c.recordDryRun(req)
+		}
+
+		failure := apiErrorBody{}
+		res, err := c.Sling.Do(req, data, &failure)
+		log.Debug(res, err)
+
+		if attempt < c.retry.MaxAttempts && c.retry.shouldRetry(req.Method, res, err) {
+			wait := c.retry.delay(attempt, res)
+			reason := fmt.Sprintf("%v", err)
+			if res != nil {
+				reason = res.Status
+			}
+			log.Info(fmt.Sprintf(
+				"%s %s failed (%s), retrying in %s [attempt %d of %d]",
+				req.Method, req.URL, reason, wait.Round(time.Millisecond), attempt+1, c.retry.MaxAttempts,
+			))
+			if e := sleep(req.Context(), wait); e != nil {
+				return res, e
+			}
+			continue
+		}
+
+		// the body of an error response may not be JSON (a proxy's 502 page
+		// for example) so a decoding error doesn't hide the APIError
+		if res != nil && res.StatusCode >= 400 {
+			return res, newAPIError(req, res, failure)
+		}
+		return res, err
 	}
-	return res, err
 }
@@ -1,15 +1,22 @@
 package conch_test
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
+	"testing"
+	"time"
 
 	"github.com/dghubble/sling"
 	"github.com/dnaeon/go-vcr/cassette"
 	"github.com/dnaeon/go-vcr/recorder"
 	"github.com/joyent/kosh/conch"
+	"github.com/joyent/kosh/conch/types"
 	"github.com/joyent/kosh/logger"
+	"github.com/stretchr/testify/assert"
 )
 
 func NewTestClient(fixture string) *conch.Client {
@@ -38,3 +45,40 @@ func NewTestClient(fixture string) *conch.Client {
 
 	return &conch.Client{Sling: s, Logger: logger.New()}
 }
+
+func TestWithContext(t *testing.T) {
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	c := conch.New(conch.API(ts.URL))
+
+	t.Run("deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err := c.WithContext(ctx).GetRackLayout(types.UUID{})
+		assert.True(t, errors.Is(err, context.DeadlineExceeded), "request hit the deadline")
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		err := c.WithContext(ctx).DeleteRack(types.UUID{})
+		assert.True(t, errors.Is(err, context.Canceled), "request was cancelled")
+	})
+
+	t.Run("builders keep the context", func(t *testing.T) {
+		type key struct{}
+		ctx := context.WithValue(context.Background(), key{}, "kosh")
+		assert.Equal(t, ctx, c.WithContext(ctx).Rack("foo").Layout().Context())
+		assert.Equal(t, context.Background(), c.Rack("foo").Context())
+	})
+}
@@ -2,6 +2,7 @@ package conch
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 
 	"github.com/joyent/kosh/conch/types"
@@ -11,18 +12,25 @@ import (
 // io.Reader and sends it to the API, it does not return the results
 func (c *Client) SendDeviceReport(r io.Reader) error {
 	report := &types.DeviceReport{}
-	json.NewDecoder(r).Decode(report)
+	if e := json.NewDecoder(r).Decode(report); e != nil {
+		return fmt.Errorf("could not read the device report: %w", e)
+	}
 	_, e := c.DeviceReport().Post(report).Send()
 	return e
 }
 
 // ValidateDeviceReport (POST /device_report?no_update_db=1) reads a new device
 // report from an io.Reader and sends it to the API returning the validation
-// results
-func (c *Client) ValidateDeviceReport(r io.Reader) (results types.ReportValidationResults) {
+// results, without storing the report or the results
+func (c *Client) ValidateDeviceReport(r io.Reader) (results types.ReportValidationResults, e error) {
 	report := &types.DeviceReport{}
-	json.NewDecoder(r).Decode(report)
-	c.DeviceReport().Post(report).Receive(&results)
+	if e = json.NewDecoder(r).Decode(report); e != nil {
+		return results, fmt.Errorf("could not read the device report: %w", e)
+	}
+	_, e = c.DeviceReport().
+		WithParams(map[string]string{"no_update_db": "1"}).
+		Post(report).
+		Receive(&results)
 	return
 }
 
@@ -32,3 +40,11 @@ func (c *Client) GetDeviceReport(id string) (report types.DeviceReportRow) {
 	c.DeviceReport(id).Receive(&report)
 	return
 }
+
+// GetDeviceReportByID (GET /device_report/:device_report_id) returns the
+// previously sent report for the given id string, or the error if it could
+// not be retrieved
+func (c *Client) GetDeviceReportByID(id string) (report types.DeviceReportRow, e error) {
+	_, e = c.DeviceReport(id).Receive(&report)
+	return
+}
@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/joyent/kosh/conch"
+	"github.com/joyent/kosh/conch/types"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -22,16 +23,16 @@ func TestDeviceReportRoutes(t *testing.T) {
 			URL:    "/device_report/",
 			Method: "POST",
 			Do: func(c *conch.Client) {
-				j, _ := json.Marshal("{}")
+				j, _ := json.Marshal(types.DeviceReport{})
 				_ = c.SendDeviceReport(bytes.NewBuffer(j))
 			},
 		},
 		{
-			URL:    "/device_report/",
+			URL:    "/device_report?no_update_db=1",
 			Method: "POST",
 			Do: func(c *conch.Client) {
-				j, _ := json.Marshal("{}")
-				_ = c.ValidateDeviceReport(bytes.NewBuffer(j))
+				j, _ := json.Marshal(types.DeviceReport{})
+				_, _ = c.ValidateDeviceReport(bytes.NewBuffer(j))
 			},
 		},
 		{
@@ -39,6 +40,11 @@ func TestDeviceReportRoutes(t *testing.T) {
 			Method: "GET",
 			Do:     func(c *conch.Client) { c.GetDeviceReport("foo") },
 		},
+		{
+			URL:    "/device_report/foo/",
+			Method: "GET",
+			Do:     func(c *conch.Client) { c.GetDeviceReportByID("foo") },
+		},
 	}
 
 	for _, test := range tests {
@@ -58,3 +64,30 @@ func TestDeviceReportRoutes(t *testing.T) {
 		})
 	}
 }
+
+func TestGetDeviceReportByIDError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "Not Found"}`))
+	}))
+	defer ts.Close()
+
+	_, e := conch.New(conch.API(ts.URL)).GetDeviceReportByID("foo")
+	assert.True(t, conch.IsNotFound(e), "%v", e)
+}
+
+func TestDeviceReportDecodeErrors(t *testing.T) {
+	seen := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = true
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	c := conch.New(conch.API(ts.URL))
+
+	_, e := c.ValidateDeviceReport(bytes.NewBufferString(`{"serial_number": `))
+	assert.NotNil(t, e)
+	assert.NotNil(t, c.SendDeviceReport(bytes.NewBufferString(`not json`)))
+	assert.False(t, seen, "nothing was sent to conch")
+}
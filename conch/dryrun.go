@@ -0,0 +1,153 @@
+package conch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DryRunRequest is a request that a client in dry run mode did not send
+type DryRunRequest struct {
+	Method string
+	URL    string
+	Body   json.RawMessage
+}
+
+// String returns the method, URL and (pretty printed) JSON body of the request
+func (r DryRunRequest) String() string {
+	s := fmt.Sprintf("%s %s", r.Method, r.URL)
+	if len(r.Body) == 0 {
+		return s
+	}
+	body := &bytes.Buffer{}
+	if err := json.Indent(body, r.Body, "", "  "); err != nil {
+		return fmt.Sprintf("%s\n%s", s, r.Body)
+	}
+	return fmt.Sprintf("%s\n%s", s, body)
+}
+
+// DryRunLog records the requests that clients in dry run mode did not send.
+// It is safe to share a single log between clients.
+type DryRunLog struct {
+	// Output, if set, receives every request as it is recorded
+	Output io.Writer
+
+	mu       sync.Mutex
+	requests []DryRunRequest
+}
+
+// NewDryRunLog returns a DryRunLog that writes every recorded request to the
+// given io.Writer. A nil writer only records the requests.
+func NewDryRunLog(w io.Writer) *DryRunLog {
+	return &DryRunLog{Output: w}
+}
+
+func (l *DryRunLog) record(r DryRunRequest) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.requests = append(l.requests, r)
+	if l.Output != nil {
+		fmt.Fprintf(l.Output, "[dry run] %s\n", r)
+	}
+}
+
+// Requests returns the requests recorded so far
+func (l *DryRunLog) Requests() []DryRunRequest {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]DryRunRequest{}, l.requests...)
+}
+
+// Summary returns a one line description of what would have changed had the
+// recorded requests been sent
+func (l *DryRunLog) Summary() string {
+	requests := l.Requests()
+	if len(requests) == 0 {
+		return "[dry run] no changes would have been made"
+	}
+
+	counts := map[string]int{}
+	for _, r := range requests {
+		counts[r.Method]++
+	}
+	methods := []string{}
+	for m, n := range counts {
+		methods = append(methods, fmt.Sprintf("%d %s", n, m))
+	}
+	sort.Strings(methods)
+
+	noun := "requests"
+	if len(requests) == 1 {
+		noun = "request"
+	}
+	return fmt.Sprintf(
+		"[dry run] %d %s not sent (%s)",
+		len(requests),
+		noun,
+		strings.Join(methods, ", "),
+	)
+}
+
+// DryRun returns an Option that puts the client in dry run mode. Requests
+// that change data (POST, PUT and DELETE) are recorded in the client's
+// DryRunLog instead of being sent. Other requests are sent as usual, so
+// lookups still work.
+//
+// A request that isn't sent gets an empty 204 response, so methods that
+// return what the API sends back for a POST, like a new token or the ID of a
+// created object, return the zero value in dry run mode.
+func DryRun() Option {
+	return DryRunWith(NewDryRunLog(nil))
+}
+
+// DryRunWith returns an Option that puts the client in dry run mode, recording
+// the requests it doesn't send in the given DryRunLog
+func DryRunWith(log *DryRunLog) Option {
+	return func(c *Client) { c.dryRun = log }
+}
+
+// DryRunLog returns the log of requests the client did not send, or nil if
+// the client isn't in dry run mode
+func (c *Client) DryRunLog() *DryRunLog {
+	return c.dryRun
+}
+
+func changesData(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// recordDryRun records the request in the dry run log and returns the response
+// a client in dry run mode gives in place of sending it
+func (c *Client) recordDryRun(req *http.Request) (*http.Response, error) {
+	r := DryRunRequest{Method: req.Method, URL: req.URL.String()}
+	if req.Body != nil {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		r.Body = bytes.TrimSpace(body)
+	}
+	c.dryRun.record(r)
+
+	return &http.Response{
+		Status:     "204 No Content (dry run)",
+		StatusCode: http.StatusNoContent,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Body:       http.NoBody,
+		Request:    req,
+	}, nil
+}
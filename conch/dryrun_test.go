@@ -0,0 +1,48 @@
+package conch_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/joyent/kosh/conch"
+	"github.com/joyent/kosh/conch/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDryRun(t *testing.T) {
+	seen := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Method+" "+r.URL.String())
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("[]"))
+	}))
+	defer ts.Close()
+
+	output := &bytes.Buffer{}
+	c := conch.New(conch.API(ts.URL), conch.DryRunWith(conch.NewDryRunLog(output)))
+
+	_, err := c.GetRackLayout(types.UUID{})
+	assert.Nil(t, err)
+
+	assert.Nil(t, c.CreateRackRole(types.RackRoleCreate{Name: "foo", RackSize: 42}))
+	assert.Nil(t, c.DeleteRack(types.UUID{}))
+	assert.Nil(t, c.UpdateHardwareProductSpecification("foo", "/bar", types.HardwareProductSpecification{}))
+
+	assert.Equal(t, []string{"GET /rack/00000000-0000-0000-0000-000000000000/layout/"}, seen)
+
+	requests := c.Rack("foo").DryRunLog().Requests()
+	assert.Equal(t, 3, len(requests))
+	assert.Equal(t, "POST", requests[0].Method)
+	assert.Equal(t, ts.URL+"/rack_role/", requests[0].URL)
+	assert.JSONEq(t, `{"name":"foo","rack_size":42}`, string(requests[0].Body))
+	assert.Equal(t, "DELETE", requests[1].Method)
+	assert.Equal(t, "PUT", requests[2].Method)
+
+	assert.Contains(t, output.String(), "POST "+ts.URL+"/rack_role/\n{\n  \"name\": \"foo\",")
+	assert.Equal(t, "[dry run] 3 requests not sent (1 DELETE, 1 POST, 1 PUT)", c.DryRunLog().Summary())
+
+	assert.Nil(t, conch.New().DryRunLog())
+	assert.Equal(t, "[dry run] no changes would have been made", conch.NewDryRunLog(nil).Summary())
+}
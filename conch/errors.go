@@ -0,0 +1,138 @@
+package conch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// APIError is returned by Send and Receive when the Conch API responds with a
+// non-successful HTTP status. It carries everything needed to report the
+// failure to the API team: the status, the server's error message and any
+// schema validation details, the request that failed, and the request ID the
+// server assigned to it.
+type APIError struct {
+	StatusCode int
+	Status     string
+
+	// Message is the "error" field of the server's JSON response body
+	Message string
+	// Details holds any schema validation failures the server reported
+	Details []APIErrorDetail
+	// Schema is the URL of the JSON schema the request was validated against
+	Schema string
+
+	Method string
+	URL    string
+
+	// RequestID is the value of the X-Request-Id response header
+	RequestID string
+	// APIVersion is the value of the X-Conch-Api response header
+	APIVersion string
+}
+
+// APIErrorDetail is a single schema validation failure as reported by the
+// Conch API
+type APIErrorDetail struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// String returns a human readable version of the validation failure
+func (d APIErrorDetail) String() string {
+	if d.Path == "" {
+		return d.Message
+	}
+	return fmt.Sprintf("%s: %s", d.Path, d.Message)
+}
+
+// apiErrorBody is the shape of the JSON error documents returned by the API
+type apiErrorBody struct {
+	Error   string          `json:"error"`
+	Details json.RawMessage `json:"details,omitempty"`
+	Schema  string          `json:"schema,omitempty"`
+}
+
+func newAPIError(req *http.Request, res *http.Response, body apiErrorBody) *APIError {
+	e := &APIError{
+		StatusCode: res.StatusCode,
+		Status:     res.Status,
+		Message:    body.Error,
+		Schema:     body.Schema,
+		RequestID:  res.Header.Get("X-Request-Id"),
+		APIVersion: res.Header.Get("X-Conch-Api"),
+	}
+	if req != nil {
+		e.Method = req.Method
+		e.URL = req.URL.String()
+	}
+
+	// details are usually a list of path/message pairs, but the API doesn't
+	// promise that so anything else is kept as a single opaque detail
+	if len(body.Details) > 0 && string(body.Details) != "null" {
+		if err := json.Unmarshal(body.Details, &e.Details); err != nil {
+			e.Details = []APIErrorDetail{{Message: string(body.Details)}}
+		}
+	}
+	return e
+}
+
+// Error satisfies the error interface
+func (e *APIError) Error() string {
+	msg := &strings.Builder{}
+	fmt.Fprintf(msg, "http error: %s", e.Status)
+	if e.Message != "" {
+		fmt.Fprintf(msg, ": %s", e.Message)
+	}
+	for _, d := range e.Details {
+		fmt.Fprintf(msg, "\n  * %s", d)
+	}
+	if e.Method != "" {
+		fmt.Fprintf(msg, "\n  request: %s %s", e.Method, e.URL)
+	}
+	if e.RequestID != "" {
+		fmt.Fprintf(msg, "\n  request id: %s", e.RequestID)
+	}
+	return msg.String()
+}
+
+func hasStatus(err error, codes ...int) bool {
+	var e *APIError
+	if !errors.As(err, &e) {
+		return false
+	}
+	for _, code := range codes {
+		if e.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// IsBadRequest returns true if the error is an APIError with a 400 status,
+// usually because the request failed schema validation
+func IsBadRequest(err error) bool { return hasStatus(err, http.StatusBadRequest) }
+
+// IsUnprocessable returns true if the error is an APIError with a 422 status,
+// a request that was well formed but couldn't be carried out as it stands
+func IsUnprocessable(err error) bool { return hasStatus(err, http.StatusUnprocessableEntity) }
+
+// IsUnauthorized returns true if the error is an APIError with a 401 status
+func IsUnauthorized(err error) bool { return hasStatus(err, http.StatusUnauthorized) }
+
+// IsForbidden returns true if the error is an APIError with a 403 status
+func IsForbidden(err error) bool { return hasStatus(err, http.StatusForbidden) }
+
+// IsNotFound returns true if the error is an APIError with a 404 status
+func IsNotFound(err error) bool { return hasStatus(err, http.StatusNotFound) }
+
+// IsConflict returns true if the error is an APIError with a 409 status
+func IsConflict(err error) bool { return hasStatus(err, http.StatusConflict) }
+
+// IsServerError returns true if the error is an APIError with a 5xx status
+func IsServerError(err error) bool {
+	var e *APIError
+	return errors.As(err, &e) && e.StatusCode >= 500
+}
@@ -0,0 +1,99 @@
+package conch_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/joyent/kosh/conch"
+	"github.com/joyent/kosh/conch/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIError(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Status  int
+		Body    string
+		Message string
+		Details []conch.APIErrorDetail
+		Check   func(error) bool
+	}{
+		{
+			Name:    "not found",
+			Status:  http.StatusNotFound,
+			Body:    `{"error":"Entity Not Found"}`,
+			Message: "Entity Not Found",
+			Check:   conch.IsNotFound,
+		},
+		{
+			Name:    "forbidden",
+			Status:  http.StatusForbidden,
+			Body:    `{"error":"Forbidden"}`,
+			Message: "Forbidden",
+			Check:   conch.IsForbidden,
+		},
+		{
+			Name:    "validation failure",
+			Status:  http.StatusBadRequest,
+			Body:    `{"error":"request did not match required format","details":[{"path":"/name","message":"Missing property."}],"schema":"/json_schema/request/RackCreate"}`,
+			Message: "request did not match required format",
+			Details: []conch.APIErrorDetail{{Path: "/name", Message: "Missing property."}},
+			Check:   conch.IsBadRequest,
+		},
+		{
+			Name:    "unprocessable",
+			Status:  http.StatusUnprocessableEntity,
+			Body:    `{"error":"rack_unit_start beyond maximum"}`,
+			Message: "rack_unit_start beyond maximum",
+			Check:   conch.IsUnprocessable,
+		},
+		{
+			Name:   "not json",
+			Status: http.StatusBadGateway,
+			Body:   `<html>Bad Gateway</html>`,
+			Check:  conch.IsServerError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("X-Request-Id", "0vfOKzb8EBJh")
+				w.Header().Set("X-Conch-Api", "v3.0.0-b4-0-gb99fbfff")
+				w.WriteHeader(test.Status)
+				w.Write([]byte(test.Body))
+			}))
+			defer ts.Close()
+
+			_, err := conch.New(conch.API(ts.URL)).GetRackByID(types.UUID{})
+
+			var apiErr *conch.APIError
+			assert.True(t, errors.As(err, &apiErr), "error is an APIError")
+			assert.True(t, test.Check(err))
+			assert.Equal(t, test.Status, apiErr.StatusCode)
+			assert.Equal(t, test.Message, apiErr.Message)
+			assert.Equal(t, test.Details, apiErr.Details)
+			assert.Equal(t, "GET", apiErr.Method)
+			assert.Equal(t, ts.URL+"/rack/00000000-0000-0000-0000-000000000000/", apiErr.URL)
+			assert.Equal(t, "0vfOKzb8EBJh", apiErr.RequestID)
+			assert.Equal(t, "v3.0.0-b4-0-gb99fbfff", apiErr.APIVersion)
+			assert.Contains(t, err.Error(), "0vfOKzb8EBJh")
+		})
+	}
+
+	t.Run("success is not an error", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"status":"ok"}`))
+		}))
+		defer ts.Close()
+
+		ping, err := conch.New(conch.API(ts.URL)).Ping()
+		assert.Nil(t, err)
+		assert.False(t, conch.IsNotFound(err))
+		assert.Equal(t, "ok", ping.Status)
+	})
+}
@@ -35,6 +35,14 @@ func (c *Client) GetHardwareProductByID(id string) (products types.HardwareProdu
 	return
 }
 
+// GetHardwareProductProfile (GET /hardware_product/:hardware_product_id_or_other)
+// returns the hardware a product is expected to have, the CPU, DIMM, NIC and
+// disk counts that are left out of HardwareProduct
+func (c *Client) GetHardwareProductProfile(id string) (profile types.HardwareProductCreate, e error) {
+	_, e = c.HardwareProduct(id).Receive(&profile)
+	return
+}
+
 // UpdateHardwareProduct (POST /hardware_product/:hardware_product_id_or_other)
 // updates the given hardware product information
 func (c *Client) UpdateHardwareProduct(id string, update types.HardwareProductUpdate) error {
@@ -34,6 +34,11 @@ func TestHardwareProductRoutes(t *testing.T) {
 			Method: "GET",
 			Do:     func(c *conch.Client) { c.GetHardwareProductByID("foo") },
 		},
+		{
+			URL:    "/hardware_product/foo/",
+			Method: "GET",
+			Do:     func(c *conch.Client) { c.GetHardwareProductProfile("foo") },
+		},
 		{
 			URL:    "/hardware_product/foo/",
 			Method: "POST",
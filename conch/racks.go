@@ -97,12 +97,22 @@ func (c *Client) DeleteRackAssignments(id types.UUID, deletes types.RackAssignme
 
 // UpdateRackPhase (POST /rack/:rack_id_or_name/phase?rack_only=<0|1>) updates
 // the rack phase and by default all the devices in the rack
-// BUG(perigrin): rackOnly currently isn't implemented
 func (c *Client) UpdateRackPhase(id types.UUID, phase types.RackPhase, rackOnly bool) error {
-	_, e := c.Rack(id.String()).Phase().Post(phase).Send()
+	r := c.Rack(id.String()).Phase()
+	if rackOnly {
+		r = r.WithParams(map[string]string{"rack_only": "1"})
+	}
+	_, e := r.Post(phase).Send()
 	return e
 }
 
+// GetRackLinks (GET /rack/:rack_id_or_name) returns the links associated
+// with the rack
+func (c *Client) GetRackLinks(id types.UUID) (links types.RackLinks, e error) {
+	_, e = c.Rack(id.String()).Receive(&links)
+	return
+}
+
 // UpdateRackLinks (POST /rack/:rack_id_or_name/links) updates the links
 // associated with the rack
 func (c *Client) UpdateRackLinks(id types.UUID, links types.RackLinks) error {
@@ -110,10 +120,16 @@ func (c *Client) UpdateRackLinks(id types.UUID, links types.RackLinks) error {
 	return e
 }
 
-// DeleteRackLinks (DELETE /rack/:rack_id_or_name/links) removes the links
-// associated with the rack
-func (c *Client) DeleteRackLinks(id types.UUID, phase types.RackLinks) error {
-	_, e := c.Rack(id.String()).Links().Delete().Send()
+// DeleteRackLinks (DELETE /rack/:rack_id_or_name/links) removes the given
+// links from the rack, or all of them if none are given
+func (c *Client) DeleteRackLinks(id types.UUID, links types.RackLinks) error {
+	r := c.Rack(id.String()).Links()
+	if len(links.Links) > 0 {
+		r = r.Delete(links)
+	} else {
+		r = r.Delete()
+	}
+	_, e := r.Send()
 	return e
 }
 
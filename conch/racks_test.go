@@ -75,12 +75,17 @@ func TestRacks(t *testing.T) {
 			},
 		},
 		{
-			URL:    "/rack/00000000-0000-0000-0000-000000000000/phase/",
+			URL:    "/rack/00000000-0000-0000-0000-000000000000/phase?rack_only=1",
 			Method: "POST",
 			Do: func(c *conch.Client) {
 				c.UpdateRackPhase(types.UUID{}, types.RackPhase{}, true)
 			},
 		},
+		{
+			URL:    "/rack/00000000-0000-0000-0000-000000000000/",
+			Method: "GET",
+			Do:     func(c *conch.Client) { c.GetRackLinks(types.UUID{}) },
+		},
 		{
 			URL:    "/rack/00000000-0000-0000-0000-000000000000/links/",
 			Method: "POST",
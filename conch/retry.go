@@ -0,0 +1,141 @@
+package conch
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy describes how the client retries requests that failed for
+// transient reasons: connection errors and 429, 502, 503 or 504 responses.
+// The zero value disables retries.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts made for a request,
+	// including the first one. Values less than 2 disable retries.
+	MaxAttempts int
+
+	// BaseDelay is the backoff before the first retry. It doubles for every
+	// subsequent retry, with full jitter applied.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the backoff between attempts. A Retry-After header sent
+	// by the server takes precedence over the computed backoff, but is
+	// capped too, so a server can't stall the client indefinitely.
+	MaxDelay time.Duration
+
+	// AllMethods allows non-idempotent requests (POST) to be retried.
+	// Otherwise they are only retried when a connection to the server
+	// could not be established, as the server never saw the request.
+	AllMethods bool
+}
+
+// DefaultRetryPolicy is a reasonable policy for interactive use
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   250 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+}
+
+// Retry returns an Option that sets the retry policy used by the client
+func Retry(policy RetryPolicy) Option {
+	return func(c *Client) { c.retry = policy }
+}
+
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+func (p RetryPolicy) shouldRetry(method string, res *http.Response, err error) bool {
+	if p.MaxAttempts < 2 {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if res == nil {
+		if err == nil {
+			return false
+		}
+		if p.AllMethods || idempotentMethods[method] {
+			return true
+		}
+		var opErr *net.OpError
+		return errors.As(err, &opErr) && opErr.Op == "dial"
+	}
+
+	switch res.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return p.AllMethods || idempotentMethods[method]
+	}
+	return false
+}
+
+// delay returns how long to wait before the given retry attempt (starting at
+// 1), preferring the server's Retry-After header when it sent one
+func (p RetryPolicy) delay(attempt int, res *http.Response) time.Duration {
+	if wait, ok := retryAfter(res); ok {
+		if p.MaxDelay > 0 && wait > p.MaxDelay {
+			wait = p.MaxDelay
+		}
+		return wait
+	}
+
+	backoff := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || backoff < p.MaxDelay); i++ {
+		backoff *= 2
+	}
+	if p.MaxDelay > 0 && backoff > p.MaxDelay {
+		backoff = p.MaxDelay
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(backoff) + 1))
+}
+
+// retryAfter parses the Retry-After header, which is either a number of
+// seconds or an HTTP date
+func retryAfter(res *http.Response) (time.Duration, bool) {
+	if res == nil {
+		return 0, false
+	}
+	header := res.Header.Get("Retry-After")
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if when, err := http.ParseTime(header); err == nil {
+		wait := time.Until(when)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+// sleep waits for the given duration or until the context is done
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
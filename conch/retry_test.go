@@ -0,0 +1,170 @@
+package conch_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/joyent/kosh/conch"
+	"github.com/joyent/kosh/conch/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetry(t *testing.T) {
+	policy := conch.RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    5 * time.Millisecond,
+	}
+
+	tests := []struct {
+		Name     string
+		Status   int
+		Header   map[string]string
+		Policy   conch.RetryPolicy
+		Do       func(c *conch.Client) error
+		Attempts int32
+		Success  bool
+	}{
+		{
+			Name:     "GET is retried until it succeeds",
+			Status:   http.StatusServiceUnavailable,
+			Policy:   policy,
+			Do:       func(c *conch.Client) error { _, e := c.GetRackLayout(types.UUID{}); return e },
+			Attempts: 3,
+			Success:  true,
+		},
+		{
+			Name:     "DELETE is retried",
+			Status:   http.StatusBadGateway,
+			Policy:   policy,
+			Do:       func(c *conch.Client) error { return c.DeleteRack(types.UUID{}) },
+			Attempts: 3,
+			Success:  true,
+		},
+		{
+			Name:     "POST is not retried by default",
+			Status:   http.StatusServiceUnavailable,
+			Policy:   policy,
+			Do:       func(c *conch.Client) error { return c.CreateRack(types.RackCreate{}) },
+			Attempts: 1,
+		},
+		{
+			Name:   "POST is retried with AllMethods",
+			Status: http.StatusServiceUnavailable,
+			Policy: conch.RetryPolicy{
+				MaxAttempts: 3,
+				BaseDelay:   time.Millisecond,
+				AllMethods:  true,
+			},
+			Do:       func(c *conch.Client) error { return c.CreateRack(types.RackCreate{}) },
+			Attempts: 3,
+			Success:  true,
+		},
+		{
+			Name:     "client errors are not retried",
+			Status:   http.StatusNotFound,
+			Policy:   policy,
+			Do:       func(c *conch.Client) error { _, e := c.GetRackLayout(types.UUID{}); return e },
+			Attempts: 1,
+		},
+		{
+			Name:     "no retries by default",
+			Status:   http.StatusServiceUnavailable,
+			Do:       func(c *conch.Client) error { _, e := c.GetRackLayout(types.UUID{}); return e },
+			Attempts: 1,
+		},
+		{
+			Name:     "Retry-After is respected",
+			Status:   http.StatusTooManyRequests,
+			Header:   map[string]string{"Retry-After": "0"},
+			Policy:   conch.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour},
+			Do:       func(c *conch.Client) error { _, e := c.GetRackLayout(types.UUID{}); return e },
+			Attempts: 3,
+			Success:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			var attempts int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// fail every attempt but the third
+				if atomic.AddInt32(&attempts, 1) < 3 {
+					for k, v := range test.Header {
+						w.Header().Set(k, v)
+					}
+					w.WriteHeader(test.Status)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte("[]"))
+			}))
+			defer ts.Close()
+
+			err := test.Do(conch.New(conch.API(ts.URL), conch.Retry(test.Policy)))
+			assert.Equal(t, test.Attempts, atomic.LoadInt32(&attempts))
+			if test.Success {
+				assert.Nil(t, err)
+			} else {
+				assert.NotNil(t, err)
+			}
+		})
+	}
+}
+
+func TestRetryAfterIsCapped(t *testing.T) {
+	var attempts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 2 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("[]"))
+	}))
+	defer ts.Close()
+
+	// without the cap the client would wait for an hour, and the context
+	// would run out first
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c := conch.New(
+		conch.API(ts.URL),
+		conch.Retry(conch.RetryPolicy{MaxAttempts: 2, MaxDelay: time.Millisecond}),
+	).WithContext(ctx)
+
+	_, err := c.GetRackLayout(types.UUID{})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestRetryConnectionError(t *testing.T) {
+	var attempts int32
+	refused := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&attempts, 1)
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	})
+
+	c := conch.New(
+		conch.API("http://conch.invalid"),
+		conch.HTTPClient(&http.Client{Transport: refused}),
+		conch.Retry(conch.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}),
+	)
+
+	// the server never saw the request so even a POST is safe to retry
+	err := c.CreateRack(types.RackCreate{})
+	assert.NotNil(t, err)
+	assert.False(t, conch.IsServerError(err))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+}
@@ -115,7 +115,11 @@ func (c *Client) DeleteRoomRackAssignments(roomID, rackID types.UUID, deletes ty
 // UpdateRoomRackPhase (POST /room/:datacenter_room_id_or_alias/rack/:rack_id_or_name/phase?rack_only=<0|1>)
 // update the rack phase for the rack in the given room
 func (c *Client) UpdateRoomRackPhase(roomID, rackID types.UUID, phase types.RackPhase, rackOnly bool) error {
-	_, e := c.Room(roomID.String()).Rack(rackID.String()).Phase().Post(phase).Send()
+	r := c.Room(roomID.String()).Rack(rackID.String()).Phase()
+	if rackOnly {
+		r = r.WithParams(map[string]string{"rack_only": "1"})
+	}
+	_, e := r.Post(phase).Send()
 	return e
 }
 
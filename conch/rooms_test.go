@@ -125,7 +125,7 @@ func TestRooms(t *testing.T) {
 			},
 		},
 		{
-			URL:    "/room/00000000-0000-0000-0000-000000000000/rack/00000000-0000-0000-0000-000000000000/phase/",
+			URL:    "/room/00000000-0000-0000-0000-000000000000/rack/00000000-0000-0000-0000-000000000000/phase?rack_only=1",
 			Method: "POST",
 			Do: func(c *conch.Client) {
 				c.UpdateRoomRackPhase(types.UUID{}, types.UUID{}, types.RackPhase{}, true)
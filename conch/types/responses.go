@@ -39,7 +39,7 @@ type BuildOrganization struct {
 	ID          UUID       `json:"id"`
 	Name        string     `json:"name"`
 	Description string     `json:"description"`
-	Admins      UsersTerse `json: "admins"`
+	Admins      UsersTerse `json:"admins"`
 	Role        Role       `json:"role"`
 }
 
@@ -343,8 +343,10 @@ type HardwareProduct struct {
 	GenerationName string                  `json:"generation_name"`
 	ID             UUID                    `json:"id"`
 	Name           MojoStandardPlaceholder `json:"name"`
-	SKU            MojoStandardPlaceholder `json:"sku"`
-	Updated        time.Time               `json:"updated"`
+	// RackUnitSize is only returned when getting a single hardware product
+	RackUnitSize PositiveInteger         `json:"rack_unit_size,omitempty"`
+	SKU          MojoStandardPlaceholder `json:"sku"`
+	Updated      time.Time               `json:"updated"`
 }
 
 // HardwareProducts is a slice of HardwareProduct structs
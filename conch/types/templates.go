@@ -10,6 +10,31 @@ import (
 	"github.com/joyent/kosh/template"
 )
 
+// table is what every Tabulable type here implements, so wide mode, full
+// UUIDs and the other table options work the same way for each of them
+type table interface {
+	tables.Tabulable
+	tables.Wide
+	tables.UUIDs
+}
+
+var (
+	_ table = Builds(nil)
+	_ table = BuildUsers(nil)
+	_ table = BuildOrganizations(nil)
+	_ table = Datacenters(nil)
+	_ table = Devices(nil)
+	_ table = HardwareVendors(nil)
+	_ table = Organizations(nil)
+	_ table = Racks(nil)
+	_ table = RackLayouts(nil)
+	_ table = RackAssignments(nil)
+	_ table = DatacenterRoomsDetailed(nil)
+	_ table = UsersTerse(nil)
+	_ table = Users(nil)
+	_ table = UserTokens(nil)
+)
+
 func (bl Builds) Len() int           { return len(bl) }
 func (bl Builds) Swap(i, j int)      { bl[i], bl[j] = bl[j], bl[i] }
 func (bl Builds) Less(i, j int) bool { return bl[i].Name < bl[j].Name }
@@ -21,9 +46,17 @@ func (bl Builds) Headers() []string {
 		"Description",
 		"Started",
 		"Completed",
+		"ID",
+		"Created",
 	}
 }
 
+// WideColumns returns the list of columns only shown in wide mode
+func (bl Builds) WideColumns() []string { return []string{"ID", "Created"} }
+
+// UUIDColumns returns the list of columns holding UUIDs
+func (bl Builds) UUIDColumns() []string { return []string{"ID"} }
+
 // ForEach iterates over each item in the list and applies a function to it
 func (bl Builds) ForEach(do func([]string)) {
 	for _, b := range bl {
@@ -32,6 +65,8 @@ func (bl Builds) ForEach(do func([]string)) {
 			b.Description,
 			template.TimeStr(b.Started),
 			template.TimeStr(b.Completed),
+			b.ID.String(),
+			template.TimeStr(b.Created),
 		})
 	}
 }
@@ -78,11 +113,17 @@ func (bu BuildUsers) Headers() []string {
 	}
 }
 
+// WideColumns returns the list of columns only shown in wide mode
+func (bu BuildUsers) WideColumns() []string { return nil }
+
+// UUIDColumns returns the list of columns holding UUIDs
+func (bu BuildUsers) UUIDColumns() []string { return []string{"ID"} }
+
 // ForEach iterates over each item in the list and applies a function to it
 func (bu BuildUsers) ForEach(do func([]string)) {
 	for _, u := range bu {
 		do([]string{
-			template.CutUUID(u.ID.String()),
+			u.ID.String(),
 			u.Name,
 			string(u.Email),
 			string(u.Role),
@@ -101,17 +142,29 @@ func (bo BuildOrganizations) Headers() []string {
 		"Name",
 		"Description",
 		"Role",
+		"Admins",
 	}
 }
 
+// WideColumns returns the list of columns only shown in wide mode
+func (bo BuildOrganizations) WideColumns() []string { return []string{"Admins"} }
+
+// UUIDColumns returns the list of columns holding UUIDs
+func (bo BuildOrganizations) UUIDColumns() []string { return []string{"ID"} }
+
 // ForEach iterates over each item in the list and applies a function to it
 func (bo BuildOrganizations) ForEach(do func([]string)) {
 	for _, o := range bo {
+		var admins []string
+		for _, a := range o.Admins {
+			admins = append(admins, string(a.Email))
+		}
 		do([]string{
-			template.CutUUID(o.ID.String()),
+			o.ID.String(),
 			o.Name,
 			o.Description,
 			string(o.Role),
+			strings.Join(admins, ", "),
 		})
 	}
 }
@@ -128,18 +181,28 @@ func (dl Datacenters) Headers() []string {
 		"Vendor Name",
 		"Region",
 		"Location",
+		"Created",
+		"Updated",
 	}
 }
 
+// WideColumns returns the list of columns only shown in wide mode
+func (dl Datacenters) WideColumns() []string { return []string{"Created", "Updated"} }
+
+// UUIDColumns returns the list of columns holding UUIDs
+func (dl Datacenters) UUIDColumns() []string { return []string{"ID"} }
+
 // ForEach iterates over each item in the list and applies a function to it
 func (dl Datacenters) ForEach(do func([]string)) {
 	for _, d := range dl {
 		do([]string{
-			template.CutUUID(d.ID.String()),
+			d.ID.String(),
 			d.Vendor,
 			d.VendorName,
 			d.Region,
 			d.Location,
+			template.TimeStr(d.Created),
+			template.TimeStr(d.Updated),
 		})
 	}
 }
@@ -280,9 +343,34 @@ func (d Devices) Headers() []string {
 		"Phase",
 		"Updated",
 		"Validated",
+		"Health",
+		"Last Seen",
+		"Build",
+		"Rack",
+		"Rack Unit",
+		"SKU",
+		"ID",
+		"Created",
+	}
+}
+
+// WideColumns returns the list of columns only shown in wide mode
+func (d Devices) WideColumns() []string {
+	return []string{
+		"Health",
+		"Last Seen",
+		"Build",
+		"Rack",
+		"Rack Unit",
+		"SKU",
+		"ID",
+		"Created",
 	}
 }
 
+// UUIDColumns returns the list of columns holding UUIDs
+func (d Devices) UUIDColumns() []string { return []string{"ID"} }
+
 // ForEach iterates over each item in the list and applies a function to it
 func (d Devices) ForEach(do func([]string)) {
 	for _, device := range d {
@@ -294,6 +382,14 @@ func (d Devices) ForEach(do func([]string)) {
 			string(device.Phase),
 			template.TimeStr(device.Updated),
 			template.TimeStr(device.Validated),
+			string(device.Health),
+			template.TimeStr(device.LastSeen),
+			device.BuildName,
+			device.RackName,
+			device.RackUnitStart,
+			string(device.Sku),
+			device.ID.String(),
+			template.TimeStr(device.Created),
 		})
 	}
 }
@@ -378,12 +474,18 @@ func (h HardwareVendors) Headers() []string {
 	}
 }
 
+// WideColumns returns the list of columns only shown in wide mode
+func (h HardwareVendors) WideColumns() []string { return nil }
+
+// UUIDColumns returns the list of columns holding UUIDs
+func (h HardwareVendors) UUIDColumns() []string { return []string{"ID"} }
+
 // ForEach iterates over each item in the list and applies a function to it
 func (h HardwareVendors) ForEach(do func([]string)) {
 	for _, v := range h {
 		do([]string{
 			string(v.Name),
-			template.CutUUID(v.ID.String()),
+			v.ID.String(),
 			template.TimeStr(v.Created),
 			template.TimeStr(v.Updated),
 		})
@@ -410,9 +512,17 @@ func (o Organizations) Headers() []string {
 		"Name",
 		"Role",
 		"Description",
+		"ID",
+		"Created",
 	}
 }
 
+// WideColumns returns the list of columns only shown in wide mode
+func (o Organizations) WideColumns() []string { return []string{"ID", "Created"} }
+
+// UUIDColumns returns the list of columns holding UUIDs
+func (o Organizations) UUIDColumns() []string { return []string{"ID"} }
+
 // ForEach iterates over each item in the list and applies a function to it
 func (o Organizations) ForEach(do func([]string)) {
 	for _, org := range o {
@@ -420,6 +530,8 @@ func (o Organizations) ForEach(do func([]string)) {
 			string(org.Name),
 			string(org.Role),
 			org.Description,
+			org.ID.String(),
+			template.TimeStr(org.Created),
 		})
 	}
 }
@@ -460,22 +572,38 @@ func (rl Racks) Headers() []string {
 		"Phase",
 		"Created",
 		"Updated",
+		"Full Name",
+		"Room Alias",
+		"Build",
 	}
 }
 
+// WideColumns returns the list of columns only shown in wide mode
+func (rl Racks) WideColumns() []string { return []string{"Full Name", "Room Alias", "Build"} }
+
+// UUIDColumns returns the list of columns holding UUIDs
+func (rl Racks) UUIDColumns() []string { return []string{"Room"} }
+
 // ForEach iterates over each item in the list and applies a function to it
 func (rl Racks) ForEach(do func([]string)) {
 	for _, r := range rl {
+		build := ""
+		if r.BuildName != nil {
+			build = fmt.Sprint(r.BuildName)
+		}
 		do([]string{
 			r.ID.String(),
 			string(r.Name),
-			template.CutUUID(r.DatacenterRoomID.String()),
+			r.DatacenterRoomID.String(),
 			string(r.RackRoleName),
 			string(r.SerialNumber),
 			string(r.AssetTag),
 			string(r.Phase),
 			template.TimeStr(r.Created),
 			template.TimeStr(r.Updated),
+			string(r.FullRackName),
+			string(r.DatacenterRoomAlias),
+			build,
 		})
 	}
 }
@@ -493,19 +621,29 @@ func (rl RackLayouts) Headers() []string {
 		"Hardware Product",
 		"Created",
 		"Updated",
+		"SKU",
+		"Rack",
 	}
 }
 
+// WideColumns returns the list of columns only shown in wide mode
+func (rl RackLayouts) WideColumns() []string { return []string{"SKU", "Rack"} }
+
+// UUIDColumns returns the list of columns holding UUIDs
+func (rl RackLayouts) UUIDColumns() []string { return []string{"ID"} }
+
 // ForEach iterates over each item in the list and applies a function to it
 func (rl RackLayouts) ForEach(do func([]string)) {
 	for _, r := range rl {
 		do([]string{
 			strconv.Itoa(int(r.RackUnitStart)),
 			strconv.Itoa(int(r.RackUnitSize)),
-			template.CutUUID(r.ID.String()),
+			r.ID.String(),
 			r.HardwareProductID.String(),
 			template.TimeStr(r.Created),
 			template.TimeStr(r.Updated),
+			string(r.Sku),
+			string(r.RackName),
 		})
 	}
 }
@@ -522,9 +660,17 @@ func (ra RackAssignments) Headers() []string {
 		"Hardware Product",
 		"Rack Unit Start",
 		"Rack Unit Size",
+		"SKU",
+		"Device ID",
 	}
 }
 
+// WideColumns returns the list of columns only shown in wide mode
+func (ra RackAssignments) WideColumns() []string { return []string{"SKU", "Device ID"} }
+
+// UUIDColumns returns the list of columns holding UUIDs
+func (ra RackAssignments) UUIDColumns() []string { return []string{"Device ID"} }
+
 // ForEach iterates over each item in the list and applies a function to it
 func (ra RackAssignments) ForEach(do func([]string)) {
 	for _, r := range ra {
@@ -534,6 +680,8 @@ func (ra RackAssignments) ForEach(do func([]string)) {
 			string(r.HardwareProductName),
 			strconv.Itoa(int(r.RackUnitStart)),
 			strconv.Itoa(int(r.RackUnitSize)),
+			string(r.Sku),
+			r.DeviceID.String(),
 		})
 	}
 }
@@ -665,15 +813,21 @@ func (dr DatacenterRoomsDetailed) Headers() []string {
 	}
 }
 
+// WideColumns returns the list of columns only shown in wide mode
+func (dr DatacenterRoomsDetailed) WideColumns() []string { return nil }
+
+// UUIDColumns returns the list of columns holding UUIDs
+func (dr DatacenterRoomsDetailed) UUIDColumns() []string { return []string{"ID", "Datacenter ID"} }
+
 // ForEach iterates over each item in the list and applies a function to it
 func (dr DatacenterRoomsDetailed) ForEach(do func([]string)) {
 	for _, r := range dr {
 		do([]string{
-			template.CutUUID(r.ID.String()),
+			r.ID.String(),
 			string(r.Alias),
 			string(r.AZ),
 			string(r.VendorName),
-			template.CutUUID(r.DatacenterID.String()),
+			r.DatacenterID.String(),
 			template.TimeStr(r.Created),
 			template.TimeStr(r.Updated),
 		})
@@ -858,15 +1012,23 @@ func (ul UsersTerse) Headers() []string {
 	return []string{
 		"Name",
 		"Email",
+		"ID",
 	}
 }
 
+// WideColumns returns the list of columns only shown in wide mode
+func (ul UsersTerse) WideColumns() []string { return []string{"ID"} }
+
+// UUIDColumns returns the list of columns holding UUIDs
+func (ul UsersTerse) UUIDColumns() []string { return []string{"ID"} }
+
 // ForEach iterates over each item in the list and applies a function to it
 func (ul UsersTerse) ForEach(do func([]string)) {
 	for _, u := range ul {
 		do([]string{
 			string(u.Name),
 			string(u.Email),
+			u.ID.String(),
 		})
 	}
 }
@@ -886,14 +1048,21 @@ func (ul Users) Headers() []string {
 		"Last Seen",
 		"Last Login",
 		"PW",
+		"Refuse Session Auth",
 	}
 }
 
+// WideColumns returns the list of columns only shown in wide mode
+func (ul Users) WideColumns() []string { return []string{"Refuse Session Auth"} }
+
+// UUIDColumns returns the list of columns holding UUIDs
+func (ul Users) UUIDColumns() []string { return []string{"ID"} }
+
 // ForEach iterates over each item in the list and applies a function to it
 func (ul Users) ForEach(do func([]string)) {
 	for _, u := range ul {
 		do([]string{
-			template.CutUUID(u.ID.String()),
+			u.ID.String(),
 			string(u.Name),
 			string(u.Email),
 			template.YesOrNo(u.IsAdmin),
@@ -901,6 +1070,7 @@ func (ul Users) ForEach(do func([]string)) {
 			template.TimeStr(u.LastSeen),
 			template.TimeStr(u.LastLogin),
 			template.YesOrNo(u.ForcePasswordChange),
+			template.YesOrNo(u.RefuseSessionAuth),
 		})
 	}
 }
@@ -962,6 +1132,12 @@ func (ul UserTokens) Headers() []string {
 	}
 }
 
+// WideColumns returns the list of columns only shown in wide mode
+func (ul UserTokens) WideColumns() []string { return nil }
+
+// UUIDColumns returns the list of columns holding UUIDs
+func (ul UserTokens) UUIDColumns() []string { return nil }
+
 // ForEach iterates over each item in the list and applies a function to it
 func (ul UserTokens) ForEach(do func([]string)) {
 	for _, u := range ul {
@@ -0,0 +1,506 @@
+/*
+Package filter evaluates simple boolean expressions against decoded JSON, so
+lists returned by the API can be narrowed down on the client.
+
+An expression compares JSON field names with literal values:
+
+	phase == "integration" && health != "pass" && hostname =~ "^sw-"
+
+Fields are named as they are in the API's JSON, nested fields are reached
+with dots (e.g. "location.rack.name"). The operators are ==, !=, <, <=, >,
+>=, =~ and !~ (regular expression match), combined with &&, || and !, and
+grouped with parentheses. Literals are double or single quoted strings,
+numbers, true, false and null.
+*/
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Filter is a parsed filter expression
+type Filter struct {
+	text string
+	root node
+}
+
+// Parse parses the text of a filter expression
+func Parse(text string) (*Filter, error) {
+	tokens, err := lex(text)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("filter: unexpected %s at position %d", t, t.pos)
+	}
+	return &Filter{text: text, root: root}, nil
+}
+
+// String returns the text the filter was parsed from
+func (f *Filter) String() string { return f.text }
+
+// Match evaluates the filter against a single decoded JSON value, as
+// produced by json.Unmarshal into an interface{}
+func (f *Filter) Match(data interface{}) (bool, error) {
+	v, err := f.root.eval(data)
+	if err != nil {
+		return false, err
+	}
+	return truthy(v), nil
+}
+
+// Apply returns a new slice of the same type as list, holding only the
+// elements that match the filter. Elements are matched using their JSON
+// encoding, so the filter uses the same field names as the API. Anything
+// that isn't a slice, like a single device, is returned as it is.
+func (f *Filter) Apply(list interface{}) (interface{}, error) {
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice {
+		return list, nil
+	}
+
+	matched := reflect.MakeSlice(v.Type(), 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		item := v.Index(i)
+
+		b, err := json.Marshal(item.Interface())
+		if err != nil {
+			return nil, err
+		}
+		var data interface{}
+		if err := json.Unmarshal(b, &data); err != nil {
+			return nil, err
+		}
+
+		ok, err := f.Match(data)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = reflect.Append(matched, item)
+		}
+	}
+	return matched.Interface(), nil
+}
+
+/* Evaluation */
+
+type node interface {
+	eval(data interface{}) (interface{}, error)
+}
+
+type literal struct{ value interface{} }
+
+func (l literal) eval(interface{}) (interface{}, error) { return l.value, nil }
+
+type field struct{ path []string }
+
+func (f field) eval(data interface{}) (interface{}, error) {
+	for _, key := range f.path {
+		obj, ok := data.(map[string]interface{})
+		if !ok {
+			return nil, nil
+		}
+		data = obj[key]
+	}
+	return data, nil
+}
+
+type not struct{ operand node }
+
+func (n not) eval(data interface{}) (interface{}, error) {
+	v, err := n.operand.eval(data)
+	if err != nil {
+		return nil, err
+	}
+	return !truthy(v), nil
+}
+
+type logical struct {
+	op          string
+	left, right node
+}
+
+func (l logical) eval(data interface{}) (interface{}, error) {
+	left, err := l.left.eval(data)
+	if err != nil {
+		return nil, err
+	}
+	// short circuit
+	if l.op == "&&" && !truthy(left) {
+		return false, nil
+	}
+	if l.op == "||" && truthy(left) {
+		return true, nil
+	}
+	right, err := l.right.eval(data)
+	if err != nil {
+		return nil, err
+	}
+	return truthy(right), nil
+}
+
+type match struct {
+	negate bool
+	left   node
+	re     *regexp.Regexp
+}
+
+func (m match) eval(data interface{}) (interface{}, error) {
+	v, err := m.left.eval(data)
+	if err != nil {
+		return nil, err
+	}
+	return m.re.MatchString(toString(v)) != m.negate, nil
+}
+
+type compare struct {
+	op          string
+	left, right node
+}
+
+func (c compare) eval(data interface{}) (interface{}, error) {
+	left, err := c.left.eval(data)
+	if err != nil {
+		return nil, err
+	}
+	right, err := c.right.eval(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch c.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	}
+
+	// ordering compares numbers as numbers and anything else as strings,
+	// which works for the API's RFC3339 timestamps
+	var cmp int
+	x, okX := toNumber(left)
+	y, okY := toNumber(right)
+	switch {
+	case okX && okY:
+		if x < y {
+			cmp = -1
+		} else if x > y {
+			cmp = 1
+		}
+	default:
+		cmp = strings.Compare(toString(left), toString(right))
+	}
+
+	switch c.op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+func equal(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	x, okX := toNumber(a)
+	y, okY := toNumber(b)
+	if okX && okY {
+		return x == y
+	}
+	return toString(a) == toString(b)
+}
+
+func toNumber(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case string:
+		f, err := strconv.ParseFloat(t, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func toString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	default:
+		b, _ := json.Marshal(t)
+		return string(b)
+	}
+}
+
+func truthy(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	case string:
+		return t != ""
+	case float64:
+		return t != 0
+	case []interface{}:
+		return len(t) > 0
+	case map[string]interface{}:
+		return len(t) > 0
+	}
+	return true
+}
+
+/* Parsing */
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("||") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logical{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("&&") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = logical{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.peek().is("!") {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return not{operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	op := p.peek()
+	if op.kind != tokenOperator {
+		return left, nil
+	}
+	switch op.text {
+	case "=~", "!~":
+		p.next()
+		t := p.next()
+		if t.kind != tokenString {
+			return nil, fmt.Errorf("filter: %s needs a quoted regular expression at position %d", op.text, t.pos)
+		}
+		re, err := regexp.Compile(t.value.(string))
+		if err != nil {
+			return nil, fmt.Errorf("filter: bad regular expression at position %d: %s", t.pos, err)
+		}
+		return match{negate: op.text == "!~", left: left, re: re}, nil
+
+	case "==", "!=", "<", "<=", ">", ">=":
+		p.next()
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return compare{op: op.text, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString, tokenNumber:
+		return literal{t.value}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return literal{true}, nil
+		case "false":
+			return literal{false}, nil
+		case "null":
+			return literal{nil}, nil
+		}
+		return field{strings.Split(t.text, ".")}, nil
+	case tokenOperator:
+		if t.text == "(" {
+			n, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if closing := p.next(); !closing.is(")") {
+				return nil, fmt.Errorf("filter: expected ) at position %d", closing.pos)
+			}
+			return n, nil
+		}
+	}
+	return nil, fmt.Errorf("filter: unexpected %s at position %d", t, t.pos)
+}
+
+/* Lexing */
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+	pos   int
+}
+
+func (t token) is(op string) bool { return t.kind == tokenOperator && t.text == op }
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of filter"
+	}
+	return fmt.Sprintf("'%s'", t.text)
+}
+
+// operators, longest first so "==" isn't read as "=" "="
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!", "(", ")"}
+
+func lex(text string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(text) && text[end] != c {
+				if text[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(text) {
+				return nil, fmt.Errorf("filter: unterminated string at position %d", i)
+			}
+			raw := text[i : end+1]
+			value, err := unquote(raw)
+			if err != nil {
+				return nil, fmt.Errorf("filter: bad string at position %d: %s", i, err)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: raw, value: value, pos: i})
+			i = end + 1
+
+		case c == '-' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(text) && strings.IndexByte("0123456789.eE+-", text[end]) >= 0 {
+				end++
+			}
+			value, err := strconv.ParseFloat(text[i:end], 64)
+			if err != nil {
+				return nil, fmt.Errorf("filter: bad number '%s' at position %d", text[i:end], i)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: text[i:end], value: value, pos: i})
+			i = end
+
+		case c == '_' || isLetter(c):
+			end := i + 1
+			for end < len(text) && (text[end] == '_' || text[end] == '.' || isLetter(text[end]) || (text[end] >= '0' && text[end] <= '9')) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: text[i:end], pos: i})
+			i = end
+
+		default:
+			found := false
+			for _, op := range operators {
+				if strings.HasPrefix(text[i:], op) {
+					tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+					i += len(op)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("filter: unexpected '%c' at position %d", c, i)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(text)}), nil
+}
+
+func isLetter(c byte) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+
+// unquote handles double quoted strings with Go escapes, and single quoted
+// strings where only \' and \\ are escapes, which is handy for regular
+// expressions
+func unquote(raw string) (string, error) {
+	if raw[0] == '"' {
+		return strconv.Unquote(raw)
+	}
+	inner := raw[1 : len(raw)-1]
+	return strings.NewReplacer(`\'`, `'`, `\\`, `\`).Replace(inner), nil
+}
@@ -0,0 +1,84 @@
+package filter_test
+
+import (
+	"testing"
+
+	"github.com/joyent/kosh/conch/types"
+	"github.com/joyent/kosh/filter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter(t *testing.T) {
+	devices := types.Devices{
+		{SerialNumber: "a", Hostname: "sw-1", Phase: "integration", Health: "fail", RackUnitStart: "10"},
+		{SerialNumber: "b", Hostname: "sw-2", Phase: "integration", Health: "pass", RackUnitStart: "9"},
+		{SerialNumber: "c", Hostname: "db-1", Phase: "integration", Health: "unknown", RackUnitStart: "20"},
+		{SerialNumber: "d", Hostname: "sw-3", Phase: "production", Health: "fail"},
+	}
+
+	tests := []struct {
+		Filter   string
+		Expected []types.DeviceSerialNumber
+	}{
+		{`phase == "integration" && health != "pass" && hostname =~ "^sw-"`, []types.DeviceSerialNumber{"a"}},
+		{`phase == 'production' || health == "pass"`, []types.DeviceSerialNumber{"b", "d"}},
+		{`!(phase == "integration")`, []types.DeviceSerialNumber{"d"}},
+		{`hostname !~ '^sw-\d'`, []types.DeviceSerialNumber{"c"}},
+		{`rack_unit_start >= 10`, []types.DeviceSerialNumber{"a", "c"}},
+		{`rack_unit_start`, []types.DeviceSerialNumber{"a", "b", "c"}},
+		{`links == null && build_name == ""`, []types.DeviceSerialNumber{"a", "b", "c", "d"}},
+		{`nope == "x"`, []types.DeviceSerialNumber{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Filter, func(t *testing.T) {
+			f, err := filter.Parse(test.Filter)
+			assert.Nil(t, err)
+
+			result, err := f.Apply(devices)
+			assert.Nil(t, err)
+
+			serials := []types.DeviceSerialNumber{}
+			for _, d := range result.(types.Devices) {
+				serials = append(serials, d.SerialNumber)
+			}
+			assert.Equal(t, test.Expected, serials)
+		})
+	}
+}
+
+func TestFilterErrors(t *testing.T) {
+	for _, text := range []string{
+		`phase ==`,
+		`phase == "integration`,
+		`(phase == "a"`,
+		`hostname =~ "("`,
+		`hostname =~ name`,
+		`phase = "a"`,
+		`phase == "a" health == "b"`,
+	} {
+		_, err := filter.Parse(text)
+		assert.Error(t, err, text)
+	}
+}
+
+func TestFilterSingleObject(t *testing.T) {
+	f, err := filter.Parse(`phase == "a"`)
+	assert.Nil(t, err)
+
+	// a single object is shown whether it matches or not, --filter is for
+	// lists
+	device := types.Device{SerialNumber: "a", Phase: "production"}
+	result, err := f.Apply(device)
+	assert.Nil(t, err)
+	assert.Equal(t, device, result)
+
+	result, err = f.Apply(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, result)
+
+	// a list is still filtered
+	result, err = f.Apply(types.Devices{device})
+	assert.Nil(t, err)
+	assert.Equal(t, types.Devices{}, result)
+}
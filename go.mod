@@ -12,5 +12,6 @@ require (
 	github.com/olekukonko/tablewriter v0.0.1
 	github.com/qri-io/jsonschema v0.2.0
 	github.com/stretchr/testify v1.4.0
-	gopkg.in/yaml.v2 v2.2.4 // indirect
+	golang.org/x/term v0.0.0-20201210144234-2321bbc49cbf
+	gopkg.in/yaml.v2 v2.2.4
 )
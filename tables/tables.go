@@ -4,8 +4,12 @@ Package tables renders tabular data.
 package tables
 
 import (
+	"encoding/csv"
+	"fmt"
 	"io"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/olekukonko/tablewriter"
@@ -36,14 +40,238 @@ type Tabulable interface {
 // Render takess a Tabulable struct and renders it into markdown compatible
 // string
 func Render(list Tabulable) string {
-	sort.Sort(list)
+	s, _ := RenderWith(list, Options{})
+	return s
+}
+
+// RenderWith renders a Tabulable struct into a markdown compatible string,
+// applying the given options
+func RenderWith(list Tabulable, opts Options) (string, error) {
+	headers, rows, e := Rows(list, opts)
+	if e != nil {
+		return "", e
+	}
+
+	tableString := &strings.Builder{}
+	table := NewTable(tableString)
+
+	if !opts.NoHeaders {
+		table.SetHeader(headers)
+	}
+	table.AppendBulk(rows)
+
+	table.Render()
+	return tableString.String(), nil
+}
+
+// RenderMarkdown renders a Tabulable struct into a strict markdown table,
+// applying the given options. Unlike RenderWith the headers are left as they
+// are, cells are left aligned and any pipes in them are escaped, so the table
+// survives being pasted into a document.
+func RenderMarkdown(list Tabulable, opts Options) (string, error) {
+	headers, rows, e := Rows(list, opts)
+	if e != nil {
+		return "", e
+	}
 
 	tableString := &strings.Builder{}
 	table := NewTable(tableString)
+	table.SetAutoFormatHeaders(false)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
 
-	table.SetHeader(list.Headers())
-	list.ForEach(table.Append)
+	escape := strings.NewReplacer("|", `\|`)
+	if !opts.NoHeaders {
+		for n, h := range headers {
+			headers[n] = escape.Replace(h)
+		}
+		table.SetHeader(headers)
+	}
+	for _, row := range rows {
+		for n, cell := range row {
+			row[n] = escape.Replace(cell)
+		}
+		table.Append(row)
+	}
 
 	table.Render()
-	return tableString.String()
+	return tableString.String(), nil
+}
+
+// RenderDelimited writes a Tabulable struct to the writer as delimited text,
+// applying the given options. Use ',' for CSV or '\t' for TSV.
+func RenderDelimited(w io.Writer, list Tabulable, comma rune, opts Options) error {
+	headers, rows, e := Rows(list, opts)
+	if e != nil {
+		return e
+	}
+
+	out := csv.NewWriter(w)
+	out.Comma = comma
+
+	if !opts.NoHeaders {
+		if e := out.Write(headers); e != nil {
+			return e
+		}
+	}
+	if e := out.WriteAll(rows); e != nil {
+		return e
+	}
+	return out.Error()
+}
+
+// Wide is implemented by Tabulable types that have columns which are only
+// shown in wide mode. Those columns are still included in Headers and
+// ForEach, they're hidden unless Options.Wide is set or they're asked for by
+// name.
+type Wide interface {
+	WideColumns() []string
+}
+
+// UUIDs is implemented by Tabulable types with UUID columns. ForEach writes
+// those columns in full, they're cut down to their first group unless
+// Options.FullUUIDs is set.
+type UUIDs interface {
+	UUIDColumns() []string
+}
+
+var uuidPrefix = regexp.MustCompile("^(.+?)-")
+
+// CutUUID trims a UUID down to a short readable version
+func CutUUID(id string) string {
+	bits := uuidPrefix.FindStringSubmatch(id)
+	if len(bits) > 0 {
+		return bits[1]
+	}
+	return id
+}
+
+// Options control which rows and columns of a Tabulable are rendered and in
+// which order
+type Options struct {
+	// Columns is the list of columns to show, in order, by name. Names are
+	// matched against the headers ignoring case, with spaces written as
+	// underscores (e.g. "last_seen" for "Last Seen").
+	Columns []string
+	// SortBy is the name of the column to sort the rows by, instead of the
+	// type's default order
+	SortBy  string
+	Reverse bool
+	// NoHeaders leaves out the header row
+	NoHeaders bool
+	// Wide shows the columns listed by the Wide interface
+	Wide bool
+	// FullUUIDs shows the columns listed by the UUIDs interface in full
+	FullUUIDs bool
+}
+
+// ColumnName returns the name used to refer to a header in Options, e.g.
+// "last_seen" for "Last Seen"
+func ColumnName(header string) string {
+	return strings.ToLower(strings.Join(strings.Fields(header), "_"))
+}
+
+// Rows applies the options to the list and returns the headers and rows to
+// be rendered
+func Rows(list Tabulable, opts Options) (headers []string, rows [][]string, err error) {
+	sort.Sort(list)
+
+	all := list.Headers()
+	index := make(map[string]int, len(all))
+	for i, h := range all {
+		index[ColumnName(h)] = i
+	}
+	lookup := func(name string) (int, error) {
+		i, ok := index[ColumnName(name)]
+		if !ok {
+			names := make([]string, len(all))
+			for n, h := range all {
+				names[n] = ColumnName(h)
+			}
+			return 0, fmt.Errorf("unknown column '%s', expected one of: %s", name, strings.Join(names, ", "))
+		}
+		return i, nil
+	}
+
+	var columns []int
+	if len(opts.Columns) > 0 {
+		for _, name := range opts.Columns {
+			i, e := lookup(name)
+			if e != nil {
+				return nil, nil, e
+			}
+			columns = append(columns, i)
+		}
+	} else {
+		hidden := map[int]bool{}
+		if w, ok := list.(Wide); ok && !opts.Wide {
+			for _, name := range w.WideColumns() {
+				if i, ok := index[ColumnName(name)]; ok {
+					hidden[i] = true
+				}
+			}
+		}
+		for i := range all {
+			if !hidden[i] {
+				columns = append(columns, i)
+			}
+		}
+	}
+
+	list.ForEach(func(row []string) { rows = append(rows, row) })
+
+	if u, ok := list.(UUIDs); ok && !opts.FullUUIDs {
+		for _, name := range u.UUIDColumns() {
+			i, ok := index[ColumnName(name)]
+			if !ok {
+				continue
+			}
+			for _, row := range rows {
+				if i < len(row) {
+					row[i] = CutUUID(row[i])
+				}
+			}
+		}
+	}
+
+	if opts.SortBy != "" {
+		i, e := lookup(opts.SortBy)
+		if e != nil {
+			return nil, nil, e
+		}
+		sort.SliceStable(rows, func(a, b int) bool {
+			return lessCell(rows[a][i], rows[b][i])
+		})
+	}
+	if opts.Reverse {
+		for a, b := 0, len(rows)-1; a < b; a, b = a+1, b-1 {
+			rows[a], rows[b] = rows[b], rows[a]
+		}
+	}
+
+	headers = pick(all, columns)
+	for n, row := range rows {
+		rows[n] = pick(row, columns)
+	}
+	return headers, rows, nil
+}
+
+func pick(row []string, columns []int) []string {
+	picked := make([]string, len(columns))
+	for n, i := range columns {
+		if i < len(row) {
+			picked[n] = row[i]
+		}
+	}
+	return picked
+}
+
+// lessCell compares two cells, numerically if they're both numbers
+func lessCell(a, b string) bool {
+	x, errA := strconv.ParseFloat(a, 64)
+	y, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		return x < y
+	}
+	return a < b
 }
@@ -0,0 +1,72 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testList is a list of devices, some of whose columns are wide
+type testList struct {
+	rows [][]string
+	wide []string
+}
+
+func (l testList) Len() int           { return len(l.rows) }
+func (l testList) Swap(i, j int)      { l.rows[i], l.rows[j] = l.rows[j], l.rows[i] }
+func (l testList) Less(i, j int) bool { return l.rows[i][0] < l.rows[j][0] }
+
+func (l testList) Headers() []string { return []string{"Serial", "Phase", "Last Seen"} }
+
+func (l testList) ForEach(do func([]string)) {
+	for _, row := range l.rows {
+		do(append([]string{}, row...))
+	}
+}
+
+func (l testList) WideColumns() []string { return l.wide }
+
+func TestRowsWide(t *testing.T) {
+	rows := [][]string{
+		{"SN1", "integration", "2020-01-01"},
+		{"SN2", "production", "2020-01-02"},
+	}
+
+	tests := []struct {
+		Name            string
+		Wide            []string
+		Opts            Options
+		ExpectedHeaders []string
+		ExpectedRows    [][]string
+	}{
+		{
+			Name:            "hidden",
+			Wide:            []string{"last seen"},
+			ExpectedHeaders: []string{"Serial", "Phase"},
+			ExpectedRows:    [][]string{{"SN1", "integration"}, {"SN2", "production"}},
+		},
+		{
+			Name:            "shown with --wide",
+			Wide:            []string{"last seen"},
+			Opts:            Options{Wide: true},
+			ExpectedHeaders: []string{"Serial", "Phase", "Last Seen"},
+			ExpectedRows:    rows,
+		},
+		{
+			// a misspelled column hides nothing, rather than the first one
+			Name:            "misspelled",
+			Wide:            []string{"last_sene"},
+			ExpectedHeaders: []string{"Serial", "Phase", "Last Seen"},
+			ExpectedRows:    rows,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			headers, got, err := Rows(testList{rows: rows, wide: test.Wide}, test.Opts)
+			assert.Nil(t, err)
+			assert.Equal(t, test.ExpectedHeaders, headers)
+			assert.Equal(t, test.ExpectedRows, got)
+		})
+	}
+}
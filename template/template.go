@@ -6,8 +6,11 @@ package template
 
 import (
 	"bytes"
-	"html/template"
-	"regexp"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
 	"time"
 
 	"github.com/joyent/kosh/tables"
@@ -25,12 +28,7 @@ func YesOrNo(p bool) string {
 
 // CutUUID - trims a UUID down to a short readable version
 func CutUUID(id string) string {
-	re := regexp.MustCompile("^(.+?)-")
-	bits := re.FindStringSubmatch(id)
-	if len(bits) > 0 {
-		return bits[1]
-	}
-	return id
+	return tables.CutUUID(id)
 }
 
 // TimeStr formats a time value into something human readable
@@ -46,12 +44,78 @@ func Table(t tables.Tabulable) string {
 	return tables.Render(t)
 }
 
+// Join joins the elements of any slice into a string, with sep between each
+// of them
+func Join(sep string, list interface{}) string {
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return fmt.Sprint(list)
+	}
+	items := make([]string, v.Len())
+	for i := range items {
+		items[i] = fmt.Sprint(v.Index(i).Interface())
+	}
+	return strings.Join(items, sep)
+}
+
+// Default returns def if the value is empty (the zero value of its type, or
+// an empty slice or map), otherwise it returns the value
+func Default(def, value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() || v.IsZero() {
+		return def
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		if v.Len() == 0 {
+			return def
+		}
+	}
+	return value
+}
+
+// JSON encodes the value as JSON
+func JSON(value interface{}) (string, error) {
+	b, err := json.Marshal(value)
+	return string(b), err
+}
+
+// Since returns how long ago the time was, e.g. "3h2m10s"
+func Since(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return time.Since(t).Round(time.Second).String()
+}
+
+// Upper returns the value as an upper case string
+func Upper(value interface{}) string {
+	return strings.ToUpper(fmt.Sprint(value))
+}
+
+// Truncate shortens the value's string form to at most length characters
+func Truncate(length int, value interface{}) string {
+	s := fmt.Sprint(value)
+	runes := []rune(s)
+	if len(runes) <= length {
+		return s
+	}
+	return string(runes[:length])
+}
+
 // NewTemplate returns a new template instance
 func NewTemplate() *template.Template {
 	return template.New("wat").Funcs(template.FuncMap{
 		"CutUUID": CutUUID, // func(id string) string { return CutUUID(id) },
 		"TimeStr": func(t time.Time) string { return TimeStr(t) },
 		"Table":   Table,
+
+		"join":     Join,
+		"upper":    Upper,
+		"default":  Default,
+		"json":     JSON,
+		"since":    Since,
+		"truncate": Truncate,
 	})
 }
 
@@ -75,3 +139,18 @@ func Render(data Templated) (string, error) {
 
 	return buf.String(), nil
 }
+
+// Execute renders the data with the given template text, for templates
+// supplied by the user
+func Execute(text string, data interface{}) (string, error) {
+	t, err := NewTemplate().Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	buf := new(bytes.Buffer)
+	if err := t.Execute(buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}